@@ -0,0 +1,99 @@
+// Package crypt decrypts assets encrypted by flinch-cli.
+//
+// Encrypted files are sealed individually with AES-GCM, so that they can be stored in a directory, embedded
+// in the binary or packed, and decrypted transparently by wrapping the filesystem that serves them with NewFS.
+// The slash-separated path of the file is authenticated along with the contents, so an encrypted file moved
+// or renamed to stand in for another asset is detected as tampered.
+//
+// An encrypted file is laid out as follows:
+//
+//	magic       [4]byte  "FLEN"
+//	version     uint8
+//	nonce       [12]byte
+//	ciphertext  the sealed contents, followed by the 16 byte GCM tag
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// Magic identifies an encrypted file.
+	Magic = "FLEN"
+
+	// Version is the encrypted file format version understood by this package.
+	Version uint8 = 2
+
+	headerSize = 4 + 1 + nonceSize
+	nonceSize  = 12
+)
+
+var (
+	// ErrTampered is returned when an encrypted file fails authentication, because its contents were
+	// modified, it was moved or renamed, or it was encrypted with a different key. Files that are not
+	// encrypted are reported as tampered too.
+	ErrTampered = errors.New("crypt: asset has been tampered with or was encrypted with a different key")
+
+	// ErrKey is returned when a key is not a valid AES key.
+	ErrKey = errors.New("crypt: key must be 16, 24 or 32 bytes")
+)
+
+// ParseKey decodes a hex-encoded AES key of 16, 24 or 32 bytes.
+func ParseKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKey, err)
+	}
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, ErrKey
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether the contents read from r start with the header of an encrypted file.
+func IsEncrypted(r io.Reader) (bool, error) {
+	header := make([]byte, len(Magic))
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(header) == Magic, nil
+}
+
+// Open decrypts the contents of an encrypted file with the given name.
+//
+// The name is the slash-separated path the file was sealed with, which is its path within the filesystem
+// serving it. Authentication failures are reported as ErrTampered.
+func Open(aead cipher.AEAD, name string, data []byte) ([]byte, error) {
+	if len(data) < headerSize+aead.Overhead() || string(data[:4]) != Magic {
+		return nil, ErrTampered
+	}
+	if data[4] != Version {
+		return nil, fmt.Errorf("crypt: unsupported version %d", data[4])
+	}
+
+	nonce := data[5:headerSize]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], []byte(name))
+	if err != nil {
+		return nil, ErrTampered
+	}
+
+	return plaintext, nil
+}
+
+// NewAEAD creates the AES-GCM cipher used to decrypt files with the key.
+func NewAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKey, err)
+	}
+	return cipher.NewGCM(block)
+}
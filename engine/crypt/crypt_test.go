@@ -0,0 +1,135 @@
+package crypt_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/adm87/flinch/engine/crypt"
+	"github.com/adm87/flinch/engine/resources"
+)
+
+var key = bytes.Repeat([]byte{1}, 32)
+
+// seal encrypts data for the file at name, as flinch-cli does.
+func seal(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+
+	aead, err := crypt.NewAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+
+	out := append([]byte(crypt.Magic), crypt.Version)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(name))
+}
+
+// assetPathFS serves the files of a MapFS by identifier, as a pack does.
+type assetPathFS struct {
+	fstest.MapFS
+	paths map[resources.Asset]string
+}
+
+func (f assetPathFS) OpenAsset(asset resources.Asset) (fs.File, error) {
+	return f.Open(f.paths[asset])
+}
+
+func (f assetPathFS) StatAsset(asset resources.Asset) (fs.FileInfo, error) {
+	return fs.Stat(f.MapFS, f.paths[asset])
+}
+
+func (f assetPathFS) AssetPath(asset resources.Asset) (string, bool) {
+	name, exists := f.paths[asset]
+	return name, exists
+}
+
+func readFile(open func() (fs.File, error)) ([]byte, error) {
+	file, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func TestFSAuthenticatesPath(t *testing.T) {
+	sealed := seal(t, "levels/a/map.tmx", []byte("map"))
+
+	// The same file copied to another directory must not stand in for the asset there.
+	decrypting, err := crypt.NewFS(fstest.MapFS{
+		"levels/a/map.tmx": {Data: sealed},
+		"levels/b/map.tmx": {Data: sealed},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(decrypting, "levels/a/map.tmx")
+	if err != nil || string(data) != "map" {
+		t.Fatalf("got (%q, %v), want the decrypted contents", data, err)
+	}
+	if _, err := fs.ReadFile(decrypting, "levels/b/map.tmx"); !errors.Is(err, crypt.ErrTampered) {
+		t.Fatalf("got error %v for a moved file, want ErrTampered", err)
+	}
+}
+
+func TestFSAuthenticatesAssetPath(t *testing.T) {
+	sealed := seal(t, "levels/a/map.tmx", []byte("map"))
+
+	decrypting, err := crypt.NewFS(assetPathFS{
+		MapFS: fstest.MapFS{
+			"levels/a/map.tmx": {Data: sealed},
+			"levels/b/map.tmx": {Data: sealed},
+		},
+		paths: map[resources.Asset]string{1: "levels/a/map.tmx", 2: "levels/b/map.tmx"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	afs, ok := decrypting.(resources.AssetFS)
+	if !ok {
+		t.Fatal("decrypting filesystem does not look up assets by identifier")
+	}
+
+	data, err := readFile(func() (fs.File, error) { return afs.OpenAsset(1) })
+	if err != nil || string(data) != "map" {
+		t.Fatalf("got (%q, %v), want the decrypted contents", data, err)
+	}
+	if _, err := readFile(func() (fs.File, error) { return afs.OpenAsset(2) }); !errors.Is(err, crypt.ErrTampered) {
+		t.Fatalf("got error %v for a moved asset, want ErrTampered", err)
+	}
+	if _, err := afs.OpenAsset(3); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v for an unknown asset, want fs.ErrNotExist", err)
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want bool
+	}{
+		"sealed": {seal(t, "a.png", []byte("a")), true},
+		"plain":  {[]byte("\x89PNG\r\n"), false},
+		"short":  {[]byte("FL"), false},
+		"empty":  {nil, false},
+	}
+
+	for name, test := range tests {
+		got, err := crypt.IsEncrypted(bytes.NewReader(test.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+// AssetPathFS is a resources.AssetFS that also reports the path of the file of each asset, such as a pack.
+type AssetPathFS interface {
+	resources.AssetFS
+
+	// AssetPath returns the slash-separated path of the file of the asset within the filesystem.
+	AssetPath(asset resources.Asset) (string, bool)
+}
+
+// NewFS wraps a filesystem of encrypted files, decrypting each file as it is opened.
+//
+// Files are authenticated against the path they are opened with, so they must be served from the same paths
+// they were sealed with. If the filesystem implements AssetPathFS, so does the returned filesystem, and assets
+// opened by identifier are authenticated against the path reported for them. Directories are served as-is.
+// Files that fail to decrypt are reported as an *fs.PathError wrapping ErrTampered.
+func NewFS(fsys fs.FS, key []byte) (fs.FS, error) {
+	aead, err := NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypting := &FS{fsys: fsys, aead: aead}
+	if afs, ok := fsys.(AssetPathFS); ok {
+		return &assetFS{FS: decrypting, afs: afs}, nil
+	}
+	return decrypting, nil
+}
+
+// FS is a filesystem that decrypts the files of an underlying filesystem.
+//
+// FS is safe for concurrent use by multiple goroutines.
+type FS struct {
+	fsys fs.FS
+	aead cipher.AEAD
+}
+
+// Open opens and decrypts the named file.
+func (f *FS) Open(name string) (fs.File, error) {
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.decrypt(name, file)
+}
+
+// ReadDir reads the named directory of the underlying filesystem.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, name)
+}
+
+// decrypt reads an encrypted file and returns a file serving its decrypted contents.
+func (f *FS) decrypt(name string, file fs.File) (fs.File, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Directories are not encrypted.
+	if info.IsDir() {
+		return file, nil
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := Open(f.aead, name, data)
+	if err != nil {
+		return nil, &fs.PathError{Op: "decrypt", Path: name, Err: err}
+	}
+
+	return &decryptedFile{
+		Reader: bytes.NewReader(plaintext),
+		info:   decryptedInfo{FileInfo: info, size: int64(len(plaintext))},
+	}, nil
+}
+
+// assetFS is an FS over a filesystem that looks up assets by identifier.
+type assetFS struct {
+	*FS
+	afs AssetPathFS
+}
+
+func (f *assetFS) OpenAsset(asset resources.Asset) (fs.File, error) {
+	name, exists := f.afs.AssetPath(asset)
+	if !exists {
+		return nil, &fs.PathError{Op: "open", Path: fmt.Sprintf("0x%x", asset), Err: fs.ErrNotExist}
+	}
+
+	file, err := f.afs.OpenAsset(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.decrypt(name, file)
+}
+
+func (f *assetFS) AssetPath(asset resources.Asset) (string, bool) {
+	return f.afs.AssetPath(asset)
+}
+
+// StatAsset returns file information for the encrypted file of the asset, whose size includes the
+// encryption overhead.
+func (f *assetFS) StatAsset(asset resources.Asset) (fs.FileInfo, error) {
+	return f.afs.StatAsset(asset)
+}
+
+type decryptedFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *decryptedFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *decryptedFile) Close() error               { return nil }
+
+// decryptedInfo reports the size of the decrypted contents of a file.
+type decryptedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (fi decryptedInfo) Size() int64 { return fi.size }
@@ -26,6 +26,16 @@ func NewContext(ctx context.Context, writer io.Writer) *Context {
 	}
 }
 
+// WithContext returns a shallow copy of ctx whose embedded context.Context is replaced with c.
+//
+// The copy shares all systems with the original, making it suitable for deriving cancellable
+// or deadline-bound contexts for background work.
+func (ctx *Context) WithContext(c context.Context) *Context {
+	clone := *ctx
+	clone.Context = c
+	return &clone
+}
+
 func (ctx *Context) Update() error {
 	// Update time before any other systems
 	ctx.time.Tick()
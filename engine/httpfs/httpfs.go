@@ -0,0 +1,365 @@
+// Package httpfs serves asset files fetched from an HTTP server, such as `flinch-cli serve-assets`.
+//
+// It lets the game run on another machine than the one the assets are edited on: the ResourceSystem reads
+// its files through an FS pointed at the workstation, and hot reloading picks up edits as they are saved.
+//
+// The protocol is plain HTTP. Files are requested by their slash-separated path under the base URL:
+//
+//	GET  <base>/<path>   the contents of the file, with its ETag, Last-Modified and Content-Length
+//	HEAD <base>/<path>   the same headers without the contents
+//
+// Requests for a file already cached carry its ETag in If-None-Match, and a 304 Not Modified response
+// serves the cached contents. Directories are returned as a JSON document of DirContentType:
+//
+//	{"entries": [{"name": "SampleA.png", "dir": false, "size": 1024, "mod_time": "2006-01-02T15:04:05Z"}]}
+//
+// A 404 Not Found response is reported as fs.ErrNotExist.
+package httpfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirContentType is the content type of directory listings.
+const DirContentType = "application/vnd.flinch.directory+json"
+
+// DefaultTimeout is the timeout of requests made with the default client.
+const DefaultTimeout = 10 * time.Second
+
+// ErrStatus is returned when the server responds with an unexpected status.
+var ErrStatus = errors.New("httpfs: unexpected response status")
+
+// Options defines configuration options for an FS.
+type Options struct {
+	// Client makes the requests of the FS. If nil, a client with DefaultTimeout is used.
+	Client *http.Client
+
+	// MaxAge is the time a cached file is served without asking the server whether it changed. Zero
+	// revalidates cached files on every open and stat, so that edits are seen as soon as they are saved.
+	MaxAge time.Duration
+
+	// ServeStale serves cached files when the server cannot be reached, rather than failing.
+	ServeStale bool
+}
+
+// FS is a filesystem that fetches files from an HTTP server.
+//
+// Fetched files are cached in memory along with their ETag, so that unchanged files are not transferred
+// again. Files served without an ETag are not cached.
+//
+// FS is safe for concurrent use by multiple goroutines.
+type FS struct {
+	base    *url.URL
+	options Options
+
+	cache map[string]*cachedFile
+	mu    sync.Mutex
+}
+
+type cachedFile struct {
+	info      fileInfo
+	etag      string
+	data      []byte
+	validated time.Time // Last time the server confirmed the cached file is current
+}
+
+// New creates an FS fetching files from the given base URL.
+func New(baseURL string, options Options) (*FS, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("httpfs: unsupported URL scheme %q", base.Scheme)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &FS{
+		base:    base,
+		options: options,
+		cache:   make(map[string]*cachedFile),
+	}, nil
+}
+
+// Open fetches the named file.
+func (f *FS) Open(name string) (fs.File, error) {
+	entry, err := f.fetch("open", http.MethodGet, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.info.IsDir() {
+		entries, err := decodeDir(entry.data)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &dirFile{info: entry.info, entries: entries}, nil
+	}
+
+	return &file{Reader: bytes.NewReader(entry.data), info: entry.info}, nil
+}
+
+// Stat returns file information for the named file, without fetching its contents unless they changed.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	entry, err := f.fetch("stat", http.MethodHead, name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.info, nil
+}
+
+// ReadDir fetches the listing of the named directory, sorted by file name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := f.fetch("readdir", http.MethodGet, name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries, err := decodeDir(entry.data)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// Purge discards every cached file.
+func (f *FS) Purge() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.cache)
+}
+
+// fetch returns the named file from the cache, revalidating it with the server if needed. HEAD requests
+// only return file information, unless the cached contents are still current.
+func (f *FS) fetch(op, method, name string) (*cachedFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	cached := f.cache[name]
+	fresh := cached != nil && f.options.MaxAge > 0 && time.Since(cached.validated) < f.options.MaxAge
+	f.mu.Unlock()
+
+	if fresh {
+		return cached, nil
+	}
+
+	req, err := http.NewRequest(method, f.url(name), nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if cached != nil {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := f.options.Client.Do(req)
+	if err != nil {
+		if cached != nil && f.options.ServeStale {
+			return cached, nil
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("%w: %s", ErrStatus, resp.Status)}
+		}
+		f.mu.Lock()
+		cached.validated = time.Now()
+		f.mu.Unlock()
+		return cached, nil
+
+	case http.StatusOK:
+
+	case http.StatusNotFound:
+		f.forget(name)
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+
+	default:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("%w: %s", ErrStatus, resp.Status)}
+	}
+
+	entry := &cachedFile{
+		info:      infoFromHeader(name, resp),
+		etag:      resp.Header.Get("ETag"),
+		validated: time.Now(),
+	}
+
+	if method == http.MethodHead {
+		if cached != nil && entry.etag != "" && entry.etag == cached.etag {
+			return cached, nil
+		}
+
+		// The cached contents are outdated, and a HEAD request does not provide the new ones.
+		f.forget(name)
+		return entry, nil
+	}
+
+	if entry.data, err = io.ReadAll(resp.Body); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if !entry.info.dir {
+		entry.info.size = int64(len(entry.data))
+	}
+
+	if entry.etag != "" {
+		f.mu.Lock()
+		f.cache[name] = entry
+		f.mu.Unlock()
+	} else {
+		f.forget(name)
+	}
+
+	return entry, nil
+}
+
+func (f *FS) forget(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.cache, name)
+}
+
+// url returns the URL of the named file. The root directory is requested with a trailing slash.
+func (f *FS) url(name string) string {
+	if name == "." {
+		u := f.base.JoinPath()
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		return u.String()
+	}
+	return f.base.JoinPath(strings.Split(name, "/")...).String()
+}
+
+// infoFromHeader returns file information from the headers of a response.
+func infoFromHeader(name string, resp *http.Response) fileInfo {
+	info := fileInfo{
+		name: path.Base(name),
+		dir:  resp.Header.Get("Content-Type") == DirContentType,
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && !info.dir {
+		info.size = size
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.modTime = modTime
+	}
+	return info
+}
+
+// ============================== Directories ==============================
+
+type dirDocument struct {
+	Entries []dirEntry `json:"entries"`
+}
+
+type dirEntry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// decodeDir decodes a directory listing, sorted by file name.
+func decodeDir(data []byte) ([]fs.DirEntry, error) {
+	document := dirDocument{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid directory listing: %w", err)
+	}
+
+	entries := make([]fs.DirEntry, 0, len(document.Entries))
+	for _, e := range document.Entries {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{
+			name:    e.Name,
+			size:    e.Size,
+			modTime: e.ModTime,
+			dir:     e.Dir,
+		}))
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// ============================== Files ==============================
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type file struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+type dirFile struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,313 @@
+package httpfs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/adm87/flinch/engine/httpfs"
+)
+
+// assetServer serves in-memory files with the protocol of `flinch-cli serve-assets`.
+type assetServer struct {
+	files    map[string][]byte
+	versions map[string]int // Version of each file, from which its ETag is derived
+	requests []request
+	mu       sync.Mutex
+}
+
+// request records a request received by an assetServer.
+type request struct {
+	method      string
+	name        string
+	ifNoneMatch string
+	status      int
+}
+
+var modTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newServer(t *testing.T, files map[string][]byte) (*assetServer, *httptest.Server) {
+	t.Helper()
+
+	s := &assetServer{files: files, versions: make(map[string]int)}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	return s, ts
+}
+
+func (s *assetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.Trim(r.URL.Path, "/")
+	if name == "" {
+		name = "."
+	}
+
+	body, etag, isDir, exists := s.lookup(name)
+	status := http.StatusOK
+	switch {
+	case !exists:
+		status = http.StatusNotFound
+	case r.Header.Get("If-None-Match") == etag:
+		status = http.StatusNotModified
+	}
+	s.requests = append(s.requests, request{r.Method, name, r.Header.Get("If-None-Match"), status})
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	if isDir {
+		w.Header().Set("Content-Type", httpfs.DirContentType)
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// lookup returns the body and ETag of the named file or directory. It must be called with s.mu held.
+func (s *assetServer) lookup(name string) (body []byte, etag string, isDir bool, exists bool) {
+	if data, exists := s.files[name]; exists {
+		return data, fmt.Sprintf(`"%s-%d"`, name, s.versions[name]), false, true
+	}
+
+	type entry struct {
+		Name    string    `json:"name"`
+		Dir     bool      `json:"dir"`
+		Size    int64     `json:"size"`
+		ModTime time.Time `json:"mod_time"`
+	}
+
+	entries := make(map[string]entry)
+	for filePath, data := range s.files {
+		rel, ok := strings.CutPrefix(filePath, name+"/")
+		if name == "." {
+			rel, ok = filePath, true
+		}
+		if !ok {
+			continue
+		}
+		child, _, isNested := strings.Cut(rel, "/")
+		if isNested {
+			entries[child] = entry{Name: child, Dir: true, ModTime: modTime}
+		} else {
+			entries[child] = entry{Name: child, Size: int64(len(data)), ModTime: modTime}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, "", false, false
+	}
+
+	document := struct {
+		Entries []entry `json:"entries"`
+	}{}
+	for _, e := range entries {
+		document.Entries = append(document.Entries, e)
+	}
+
+	body, _ = json.Marshal(document)
+	return body, fmt.Sprintf(`"dir-%x"`, len(body)), true, true
+}
+
+// set replaces the contents of the named file, changing its ETag.
+func (s *assetServer) set(name string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = data
+	s.versions[name]++
+}
+
+// last returns the last request received for the named file.
+func (s *assetServer) last(t *testing.T, name string) request {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range slices.Backward(s.requests) {
+		if r.name == name {
+			return r
+		}
+	}
+	t.Fatalf("no request for %s", name)
+	return request{}
+}
+
+func newFS(t *testing.T, ts *httptest.Server, options httpfs.Options) *httpfs.FS {
+	t.Helper()
+
+	fsys, err := httpfs.New(ts.URL+"/assets", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+func readFile(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpenRevalidatesCachedFiles(t *testing.T) {
+	s, ts := newServer(t, map[string][]byte{"assets/a.txt": []byte("a")})
+	fsys := newFS(t, ts, httpfs.Options{})
+
+	if got := readFile(t, fsys, "a.txt"); got != "a" {
+		t.Fatalf("got %q, want a", got)
+	}
+	if r := s.last(t, "assets/a.txt"); r.status != http.StatusOK || r.ifNoneMatch != "" {
+		t.Fatalf("got request %+v, want an unconditional 200", r)
+	}
+
+	// The cached copy is revalidated, and served from the cache when the server reports it unchanged.
+	if got := readFile(t, fsys, "a.txt"); got != "a" {
+		t.Fatalf("got %q from the cache, want a", got)
+	}
+	if r := s.last(t, "assets/a.txt"); r.status != http.StatusNotModified || r.ifNoneMatch == "" {
+		t.Fatalf("got request %+v, want a 304 after If-None-Match", r)
+	}
+
+	s.set("assets/a.txt", []byte("changed"))
+	if got := readFile(t, fsys, "a.txt"); got != "changed" {
+		t.Fatalf("got %q, want the changed contents", got)
+	}
+}
+
+func TestOpenWithinMaxAge(t *testing.T) {
+	s, ts := newServer(t, map[string][]byte{"assets/a.txt": []byte("a")})
+	fsys := newFS(t, ts, httpfs.Options{MaxAge: time.Hour})
+
+	readFile(t, fsys, "a.txt")
+	s.set("assets/a.txt", []byte("changed"))
+
+	if got := readFile(t, fsys, "a.txt"); got != "a" {
+		t.Fatalf("got %q, want the cached contents within MaxAge", got)
+	}
+	if r := s.last(t, "assets/a.txt"); r.status != http.StatusOK || r.ifNoneMatch != "" {
+		t.Fatalf("got request %+v, want only the first fetch", r)
+	}
+}
+
+func TestStatAfterChangeDropsCachedFile(t *testing.T) {
+	s, ts := newServer(t, map[string][]byte{"assets/a.txt": []byte("a")})
+	fsys := newFS(t, ts, httpfs.Options{})
+
+	readFile(t, fsys, "a.txt")
+	s.set("assets/a.txt", []byte("changed"))
+
+	info, err := fsys.Stat("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len("changed")) {
+		t.Fatalf("got size %d, want the size of the changed file", info.Size())
+	}
+	if r := s.last(t, "assets/a.txt"); r.method != http.MethodHead || r.status != http.StatusOK {
+		t.Fatalf("got request %+v, want a HEAD answered with 200", r)
+	}
+
+	// The outdated copy was dropped, so the next open fetches the file without revalidating it.
+	if got := readFile(t, fsys, "a.txt"); got != "changed" {
+		t.Fatalf("got %q, want the changed contents", got)
+	}
+	if r := s.last(t, "assets/a.txt"); r.ifNoneMatch != "" {
+		t.Fatalf("got request %+v revalidating a dropped copy", r)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	s, ts := newServer(t, map[string][]byte{"assets/a.txt": []byte("a")})
+	fsys := newFS(t, ts, httpfs.Options{ServeStale: true})
+
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v from Stat, want fs.ErrNotExist", err)
+	}
+
+	// Files deleted from the server are not served from the cache.
+	readFile(t, fsys, "a.txt")
+	s.mu.Lock()
+	delete(s.files, "assets/a.txt")
+	s.mu.Unlock()
+
+	if _, err := fsys.Open("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v for a deleted file, want fs.ErrNotExist", err)
+	}
+}
+
+func TestServeStale(t *testing.T) {
+	_, ts := newServer(t, map[string][]byte{"assets/a.txt": []byte("a")})
+
+	stale := newFS(t, ts, httpfs.Options{ServeStale: true})
+	strict := newFS(t, ts, httpfs.Options{})
+	readFile(t, stale, "a.txt")
+	readFile(t, strict, "a.txt")
+
+	ts.Close()
+
+	if got := readFile(t, stale, "a.txt"); got != "a" {
+		t.Fatalf("got %q, want the cached contents while the server is down", got)
+	}
+	if _, err := fs.ReadFile(strict, "a.txt"); err == nil {
+		t.Fatal("got no error without ServeStale while the server is down")
+	}
+	if _, err := fs.ReadFile(stale, "b.txt"); err == nil {
+		t.Fatal("got no error for a file that was never cached")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	_, ts := newServer(t, map[string][]byte{
+		"assets/b.txt":        []byte("b"),
+		"assets/a.txt":        []byte("a"),
+		"assets/images/c.png": []byte("c"),
+	})
+	fsys := newFS(t, ts, httpfs.Options{})
+
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	if want := []string{"a.txt", "b.txt", "images"}; !slices.Equal(names, want) {
+		t.Fatalf("got entries %v, want %v", names, want)
+	}
+	if !entries[2].IsDir() {
+		t.Fatal("images is not reported as a directory")
+	}
+
+	if _, err := fsys.ReadDir("a.txt"); err == nil {
+		t.Fatal("got no error listing a file")
+	}
+
+	if err := fstest.TestFS(fsys, "a.txt", "b.txt", "images/c.png"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package pack reads flinch pack archives.
+//
+// A pack is a single file that bundles the assets of a directory, indexed by their resources.Asset
+// identifier and by their path. Pack files are produced by `flinch-cli pack`.
+//
+// All integers are little-endian. A pack is laid out as follows:
+//
+//	header  (32 bytes)
+//	  magic        [4]byte  "FLPK"
+//	  version      uint16
+//	  flags        uint16   reserved, must be zero
+//	  entryCount   uint32
+//	  alignment    uint32   alignment of entry data, relative to the start of the file
+//	  indexOffset  uint64
+//	  indexSize    uint64
+//	data    entry data, each entry starting on an alignment boundary
+//	index   entryCount entries
+//	  asset        uint64
+//	  offset       uint64   offset of the entry data from the start of the file
+//	  size         uint64   stored size of the entry data
+//	  rawSize      uint64   size of the entry once decompressed
+//	  compression  uint8
+//	  pathLen      uint16
+//	  path         [pathLen]byte, slash-separated and relative to the packed directory
+package pack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Magic identifies a flinch pack file.
+	Magic = "FLPK"
+
+	// Version is the pack format version understood by this package.
+	Version uint16 = 1
+
+	headerSize     = 32
+	indexEntrySize = 8 + 8 + 8 + 8 + 1 + 2
+
+	// maxDeflateRatio bounds the decompressed size of a DEFLATE entry relative to its stored size. DEFLATE
+	// cannot expand data by more than about 1032 to 1, so entries claiming more are corrupt.
+	maxDeflateRatio = 1032
+)
+
+// Compression identifies how the data of a pack entry is stored.
+type Compression uint8
+
+const (
+	CompressionNone    Compression = iota // Data is stored as-is
+	CompressionDeflate                    // Data is compressed with DEFLATE (RFC 1951)
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	default:
+		return fmt.Sprintf("compression(%d)", uint8(c))
+	}
+}
+
+// ErrFormat is returned when a file is not a valid pack.
+var ErrFormat = errors.New("pack: invalid format")
+
+type header struct {
+	version     uint16
+	flags       uint16
+	entryCount  uint32
+	alignment   uint32
+	indexOffset uint64
+	indexSize   uint64
+}
+
+func decodeHeader(b []byte) (header, error) {
+	if len(b) < headerSize || string(b[:4]) != Magic {
+		return header{}, ErrFormat
+	}
+
+	h := header{
+		version:     binary.LittleEndian.Uint16(b[4:]),
+		flags:       binary.LittleEndian.Uint16(b[6:]),
+		entryCount:  binary.LittleEndian.Uint32(b[8:]),
+		alignment:   binary.LittleEndian.Uint32(b[12:]),
+		indexOffset: binary.LittleEndian.Uint64(b[16:]),
+		indexSize:   binary.LittleEndian.Uint64(b[24:]),
+	}
+
+	if h.version != Version {
+		return header{}, fmt.Errorf("%w: unsupported version %d", ErrFormat, h.version)
+	}
+
+	return h, nil
+}
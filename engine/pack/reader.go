@@ -0,0 +1,358 @@
+package pack
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+// Reader provides access to the entries of a pack.
+//
+// Reader implements fs.FS, fs.ReadDirFS and resources.AssetFS, so it can be used directly as the
+// filesystem, or one of the layers, of a resources.ResourceSystem. Assets are then looked up by
+// their identifier without any path translation.
+//
+// Reader is safe for concurrent use by multiple goroutines.
+type Reader struct {
+	r      io.ReaderAt
+	closer io.Closer
+
+	entries []*entry
+	assets  map[resources.Asset]*entry
+	paths   map[string]*entry
+	dirs    map[string][]fs.DirEntry
+}
+
+type entry struct {
+	asset       resources.Asset
+	path        string
+	offset      int64
+	size        int64
+	rawSize     int64
+	compression Compression
+}
+
+// OpenFile opens the pack file at the given path on disk.
+//
+// The returned Reader must be closed when no longer needed.
+func OpenFile(name string) (*Reader, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	reader, err := NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader.closer = file
+
+	return reader, nil
+}
+
+// NewReader reads the index of a pack of the given size from r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	buf := make([]byte, headerSize)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+
+	h, err := decodeHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.indexOffset > uint64(size) || h.indexSize > uint64(size)-h.indexOffset {
+		return nil, fmt.Errorf("%w: index out of bounds", ErrFormat)
+	}
+
+	if uint64(h.entryCount) > h.indexSize/indexEntrySize {
+		return nil, fmt.Errorf("%w: %d entries do not fit in the index", ErrFormat, h.entryCount)
+	}
+
+	index := make([]byte, h.indexSize)
+	if _, err := r.ReadAt(index, int64(h.indexOffset)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+
+	reader := &Reader{
+		r:       r,
+		entries: make([]*entry, 0, h.entryCount),
+		assets:  make(map[resources.Asset]*entry, h.entryCount),
+		paths:   make(map[string]*entry, h.entryCount),
+		dirs:    make(map[string][]fs.DirEntry),
+	}
+
+	for range h.entryCount {
+		if len(index) < indexEntrySize {
+			return nil, fmt.Errorf("%w: truncated index", ErrFormat)
+		}
+
+		offset := binary.LittleEndian.Uint64(index[8:])
+		storedSize := binary.LittleEndian.Uint64(index[16:])
+		rawSize := binary.LittleEndian.Uint64(index[24:])
+
+		e := &entry{
+			asset:       resources.Asset(binary.LittleEndian.Uint64(index[0:])),
+			compression: Compression(index[32]),
+		}
+		pathLen := int(binary.LittleEndian.Uint16(index[33:]))
+		index = index[indexEntrySize:]
+
+		if len(index) < pathLen {
+			return nil, fmt.Errorf("%w: truncated index", ErrFormat)
+		}
+		e.path = string(index[:pathLen])
+		index = index[pathLen:]
+
+		// Sizes are checked against the archive before anything is allocated for the entry, so that a corrupt
+		// or crafted pack cannot make the reader allocate more than it could legitimately hold.
+		if offset > uint64(size) || storedSize > uint64(size)-offset {
+			return nil, fmt.Errorf("%w: entry %s out of bounds", ErrFormat, e.path)
+		}
+		if err := checkRawSize(e.compression, storedSize, rawSize); err != nil {
+			return nil, fmt.Errorf("%w: entry %s %v", ErrFormat, e.path, err)
+		}
+		e.offset, e.size, e.rawSize = int64(offset), int64(storedSize), int64(rawSize)
+
+		if !fs.ValidPath(e.path) {
+			return nil, fmt.Errorf("%w: invalid entry path %q", ErrFormat, e.path)
+		}
+
+		reader.entries = append(reader.entries, e)
+		reader.assets[e.asset] = e
+		reader.paths[e.path] = e
+	}
+
+	reader.buildDirs()
+
+	return reader, nil
+}
+
+// checkRawSize checks the decompressed size of an entry against its stored size.
+func checkRawSize(compression Compression, storedSize, rawSize uint64) error {
+	switch compression {
+	case CompressionNone:
+		if rawSize != storedSize {
+			return fmt.Errorf("size %d does not match its stored size %d", rawSize, storedSize)
+		}
+	case CompressionDeflate:
+		if rawSize > storedSize*maxDeflateRatio {
+			return fmt.Errorf("size %d exceeds the maximum DEFLATE expansion of its stored size %d", rawSize, storedSize)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying file if the Reader was created with OpenFile.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Open opens the named file or directory within the pack.
+func (r *Reader) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if e, exists := r.paths[name]; exists {
+		return r.openEntry(e)
+	}
+
+	if entries, exists := r.dirs[name]; exists {
+		return &dir{info: dirInfo{name: path.Base(name)}, entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir reads the named directory within the pack.
+func (r *Reader) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, exists := r.dirs[name]
+	if !exists {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(entries), nil
+}
+
+// OpenAsset opens the entry for the asset, without translating the asset to a path.
+func (r *Reader) OpenAsset(asset resources.Asset) (fs.File, error) {
+	e, exists := r.assets[asset]
+	if !exists {
+		return nil, &fs.PathError{Op: "open", Path: fmt.Sprintf("0x%x", asset), Err: fs.ErrNotExist}
+	}
+	return r.openEntry(e)
+}
+
+// AssetPath returns the path of the entry for the asset.
+func (r *Reader) AssetPath(asset resources.Asset) (string, bool) {
+	e, exists := r.assets[asset]
+	if !exists {
+		return "", false
+	}
+	return e.path, true
+}
+
+// StatAsset returns file information for the entry of the asset.
+func (r *Reader) StatAsset(asset resources.Asset) (fs.FileInfo, error) {
+	e, exists := r.assets[asset]
+	if !exists {
+		return nil, &fs.PathError{Op: "stat", Path: fmt.Sprintf("0x%x", asset), Err: fs.ErrNotExist}
+	}
+	return e.info(), nil
+}
+
+// Assets returns the identifiers of every asset in the pack, in the order they were packed.
+func (r *Reader) Assets() []resources.Asset {
+	assets := make([]resources.Asset, len(r.entries))
+	for i, e := range r.entries {
+		assets[i] = e.asset
+	}
+	return assets
+}
+
+func (r *Reader) openEntry(e *entry) (fs.File, error) {
+	section := io.NewSectionReader(r.r, e.offset, e.size)
+
+	switch e.compression {
+	case CompressionNone:
+		return &file{info: e.info(), ReadSeeker: section}, nil
+	case CompressionDeflate:
+		// Compressed entries are inflated up front so the returned file remains seekable.
+		inflater := flate.NewReader(section)
+		defer inflater.Close()
+
+		data := make([]byte, e.rawSize)
+		if _, err := io.ReadFull(inflater, data); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: e.path, Err: err}
+		}
+		return &file{info: e.info(), ReadSeeker: bytes.NewReader(data)}, nil
+	default:
+		return nil, &fs.PathError{Op: "open", Path: e.path, Err: fmt.Errorf("%w: unsupported %s", ErrFormat, e.compression)}
+	}
+}
+
+// buildDirs synthesizes the directory tree of the pack from its entry paths.
+func (r *Reader) buildDirs() {
+	children := map[string]map[string]fs.DirEntry{".": {}}
+
+	for _, e := range r.entries {
+		name := e.path
+		var child fs.DirEntry = fs.FileInfoToDirEntry(e.info())
+
+		for {
+			parent := path.Dir(name)
+			if _, exists := children[parent]; !exists {
+				children[parent] = make(map[string]fs.DirEntry)
+			}
+			children[parent][path.Base(name)] = child
+
+			if parent == "." {
+				break
+			}
+
+			name = parent
+			child = fs.FileInfoToDirEntry(dirInfo{name: path.Base(name)})
+		}
+	}
+
+	for name, entries := range children {
+		sorted := make([]fs.DirEntry, 0, len(entries))
+		for _, entry := range entries {
+			sorted = append(sorted, entry)
+		}
+		slices.SortFunc(sorted, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+		r.dirs[name] = sorted
+	}
+}
+
+func (e *entry) info() fs.FileInfo {
+	return fileInfo{name: path.Base(e.path), size: e.rawSize}
+}
+
+// ============================== Files ==============================
+
+type file struct {
+	io.ReadSeeker
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+type dir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(remaining), nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(remaining))
+	d.offset += n
+	return slices.Clone(remaining[:n]), nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return 0444 }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() any           { return nil }
+
+type dirInfo struct {
+	name string
+}
+
+func (di dirInfo) Name() string       { return di.name }
+func (di dirInfo) Size() int64        { return 0 }
+func (di dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (di dirInfo) ModTime() time.Time { return time.Time{} }
+func (di dirInfo) IsDir() bool        { return true }
+func (di dirInfo) Sys() any           { return nil }
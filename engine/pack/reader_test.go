@@ -0,0 +1,146 @@
+package pack_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/adm87/flinch/engine/pack"
+	"github.com/adm87/flinch/engine/resources"
+)
+
+type testEntry struct {
+	asset       resources.Asset
+	path        string
+	data        []byte // Stored data of the entry
+	compression pack.Compression
+
+	// Index fields overriding the ones computed from data, when not zero.
+	offset  uint64
+	size    uint64
+	rawSize uint64
+}
+
+// buildPack encodes a pack holding the entries, with entryCount overriding the number of entries when not
+// zero.
+func buildPack(t *testing.T, entryCount uint32, entries ...testEntry) []byte {
+	t.Helper()
+
+	buf := bytes.NewBuffer(make([]byte, 32))
+	index := &bytes.Buffer{}
+
+	for _, e := range entries {
+		offset := uint64(buf.Len())
+		buf.Write(e.data)
+
+		rawSize := uint64(len(e.data))
+		if e.compression == pack.CompressionDeflate {
+			inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(e.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rawSize = uint64(len(inflated))
+		}
+
+		binary.Write(index, binary.LittleEndian, uint64(e.asset))
+		binary.Write(index, binary.LittleEndian, cmpOr(e.offset, offset))
+		binary.Write(index, binary.LittleEndian, cmpOr(e.size, uint64(len(e.data))))
+		binary.Write(index, binary.LittleEndian, cmpOr(e.rawSize, rawSize))
+		index.WriteByte(byte(e.compression))
+		binary.Write(index, binary.LittleEndian, uint16(len(e.path)))
+		index.WriteString(e.path)
+	}
+
+	if entryCount == 0 {
+		entryCount = uint32(len(entries))
+	}
+
+	out := buf.Bytes()
+	copy(out, pack.Magic)
+	binary.LittleEndian.PutUint16(out[4:], pack.Version)
+	binary.LittleEndian.PutUint32(out[8:], entryCount)
+	binary.LittleEndian.PutUint32(out[12:], 1)
+	binary.LittleEndian.PutUint64(out[16:], uint64(len(out)))
+	binary.LittleEndian.PutUint64(out[24:], uint64(index.Len()))
+
+	return append(out, index.Bytes()...)
+}
+
+func cmpOr(override, value uint64) uint64 {
+	if override != 0 {
+		return override
+	}
+	return value
+}
+
+func deflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writer, _ := flate.NewWriter(buf, flate.BestCompression)
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReaderOpensEntries(t *testing.T) {
+	plain := []byte("plain entry")
+	compressed := bytes.Repeat([]byte("compressed entry "), 64)
+
+	data := buildPack(t, 0,
+		testEntry{asset: 1, path: "images/a.png", data: plain},
+		testEntry{asset: 2, path: "maps/b.tmx", data: deflate(t, compressed), compression: pack.CompressionDeflate},
+	)
+
+	reader, err := pack.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for asset, want := range map[resources.Asset][]byte{1: plain, 2: compressed} {
+		file, err := reader.OpenAsset(asset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("asset %d: got %q, want %q", asset, got, want)
+		}
+	}
+}
+
+func TestReaderRejectsCorruptIndex(t *testing.T) {
+	compressed := deflate(t, bytes.Repeat([]byte{0}, 4096))
+
+	tests := map[string][]byte{
+		"offset beyond archive": buildPack(t, 0, testEntry{asset: 1, path: "a", data: []byte("a"), offset: 1 << 40}),
+		"size beyond archive":   buildPack(t, 0, testEntry{asset: 1, path: "a", data: []byte("a"), size: 1 << 62}),
+		"wrapping offset":       buildPack(t, 0, testEntry{asset: 1, path: "a", data: []byte("a"), offset: 1<<64 - 1}),
+		"raw size of stored":    buildPack(t, 0, testEntry{asset: 1, path: "a", data: []byte("a"), rawSize: 1 << 32}),
+		"raw size of deflated": buildPack(t, 0, testEntry{
+			asset:       1,
+			path:        "a",
+			data:        compressed,
+			compression: pack.CompressionDeflate,
+			rawSize:     uint64(len(compressed))*1032 + 1,
+		}),
+		"entry count": buildPack(t, 1<<31, testEntry{asset: 1, path: "a", data: []byte("a")}),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := pack.NewReader(bytes.NewReader(data), int64(len(data)))
+			if !errors.Is(err, pack.ErrFormat) {
+				t.Fatalf("got error %v, want ErrFormat", err)
+			}
+		})
+	}
+}
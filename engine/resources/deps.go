@@ -0,0 +1,301 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ============================== Dependencies ==============================
+
+// DependencyScanner extracts the references an asset file makes to other assets.
+//
+// References are paths relative to the directory of the scanned asset, such as "../shared/sets/x.tsx".
+type DependencyScanner func(data []byte) ([]string, error)
+
+var (
+	scanners   = make(map[string]DependencyScanner)
+	scannersMu sync.RWMutex
+)
+
+// RegisterScanner registers a DependencyScanner for assets with the given file extensions.
+//
+// Extensions include the leading dot, e.g. ".tmx". Registering a scanner for an extension that already
+// has one replaces it.
+func RegisterScanner(scanner DependencyScanner, exts ...string) {
+	scannersMu.Lock()
+	defer scannersMu.Unlock()
+
+	for _, ext := range exts {
+		scanners[strings.ToLower(ext)] = scanner
+	}
+}
+
+// ScanXMLSources is a DependencyScanner that returns the value of every source attribute in an XML
+// document. It is suitable for Tiled maps (.tmx) and tilesets (.tsx).
+func ScanXMLSources(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	sources := make([]string, 0)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return sources, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			for _, attr := range element.Attr {
+				if attr.Name.Local == "source" {
+					sources = append(sources, attr.Value)
+				}
+			}
+		}
+	}
+}
+
+// MissingDependencyError is returned when an asset references a path that is not in the manifest.
+type MissingDependencyError struct {
+	System    string // Name of the resource system
+	From      Asset  // The asset making the reference
+	FromPath  string // Manifest path of the asset making the reference
+	Reference string // The reference as written in the asset
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("asset 0x%x (%s) in resource system %s references missing asset %q", e.From, e.FromPath, e.System, e.Reference)
+}
+
+// DependencyCycleError is returned when assets reference each other in a cycle.
+type DependencyCycleError struct {
+	System string   // Name of the resource system
+	Cycle  []Asset  // The assets forming the cycle, starting and ending with the same asset
+	Paths  []string // Manifest paths of the assets in Cycle
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle in resource system %s: %s", e.System, strings.Join(e.Paths, " -> "))
+}
+
+// ResolveReference resolves a path referenced by an asset, relative to the asset's own manifest path,
+// to the Asset it refers to.
+func (rs *ResourceSystem) ResolveReference(from Asset, reference string) (Asset, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	fromPath := rs.manifest[from]
+	target := path.Clean(path.Join(path.Dir(fromPath), reference))
+
+	asset, exists := rs.paths[target]
+	if !exists || rs.manifest[asset] != target {
+		return 0, &MissingDependencyError{
+			System:    rs.name,
+			From:      from,
+			FromPath:  fromPath,
+			Reference: reference,
+		}
+	}
+	return asset, nil
+}
+
+// DeclareDependencies records the references an asset makes to other assets.
+//
+// Loaders that discover references while decoding an asset declare them so that later batches can load
+// the asset's dependencies without scanning it again. References are resolved with ResolveReference.
+func (rs *ResourceSystem) DeclareDependencies(asset Asset, references ...string) error {
+	deps, err := rs.resolveReferences(asset, references)
+	if err != nil {
+		return err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.deps[asset] = deps
+
+	return nil
+}
+
+// Dependencies returns the assets directly referenced by an asset.
+//
+// Declared dependencies are returned as-is. Otherwise, if a DependencyScanner is registered for the
+// asset's extension, the asset is read and scanned, and the result is remembered unless the asset was
+// reloaded while it was being scanned.
+func (rs *ResourceSystem) Dependencies(ctx context.Context, asset Asset) ([]Asset, error) {
+	rs.mu.RLock()
+	deps, declared := rs.deps[asset]
+	assetPath, exists := rs.manifest[asset]
+	scan := rs.scans[asset]
+	rs.mu.RUnlock()
+
+	if declared {
+		return deps, nil
+	}
+	if !exists {
+		return nil, rs.assetError("scan", asset, ErrUnknownAsset)
+	}
+
+	scannersMu.RLock()
+	scanner, exists := scanners[strings.ToLower(path.Ext(assetPath))]
+	scannersMu.RUnlock()
+
+	if !exists {
+		return nil, nil
+	}
+
+	data, err := rs.ReadBytesContext(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	references, err := scanner(data)
+	if err != nil {
+		return nil, rs.DecodeError(asset, err)
+	}
+
+	deps, err = rs.resolveReferences(asset, references)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.scans[asset] == scan {
+		if declared, exists := rs.deps[asset]; exists {
+			return declared, nil
+		}
+		rs.deps[asset] = deps
+	}
+	return deps, nil
+}
+
+// resolveReferences resolves the references made by an asset with ResolveReference.
+func (rs *ResourceSystem) resolveReferences(asset Asset, references []string) ([]Asset, error) {
+	deps := make([]Asset, 0, len(references))
+	for _, reference := range references {
+		dep, err := rs.ResolveReference(asset, reference)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// Closure returns the given assets together with all of their transitive dependencies.
+//
+// Each asset appears exactly once, after every asset it depends on, so loading the assets in order
+// guarantees dependencies are loaded first. Cycles are reported as a *DependencyCycleError, and
+// references to paths outside the manifest as a *MissingDependencyError.
+func (rs *ResourceSystem) Closure(ctx context.Context, assets ...Asset) ([]Asset, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[Asset]int)
+	order := make([]Asset, 0, len(assets))
+	stack := make([]Asset, 0)
+
+	var visit func(asset Asset) error
+	visit = func(asset Asset) error {
+		switch state[asset] {
+		case visited:
+			return nil
+		case visiting:
+			return rs.cycleError(stack, asset)
+		}
+
+		state[asset] = visiting
+		stack = append(stack, asset)
+
+		deps, err := rs.Dependencies(ctx, asset)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[asset] = visited
+		order = append(order, asset)
+
+		return nil
+	}
+
+	for _, asset := range assets {
+		if err := visit(asset); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// CreateDependencyBatch creates a new LoadingOperation that loads the given assets together with all
+// of their transitive dependencies, each exactly once and dependencies first.
+//
+// The loader function returns the task that loads a single asset, or nil if the asset does not need
+// to be loaded by the batch. If loader is nil, assets are loaded by the Loaders registered for their
+// extensions, and assets without a registered Loader are skipped.
+func (rs *ResourceSystem) CreateDependencyBatch(ctx context.Context, loader func(asset Asset) LoadingTask, assets ...Asset) (*LoadingOperation, error) {
+	closure, err := rs.Closure(ctx, assets...)
+	if err != nil {
+		return nil, err
+	}
+
+	if loader == nil {
+		loader = func(asset Asset) LoadingTask {
+			if _, err := rs.registration(asset); err != nil {
+				return nil
+			}
+			return NewLoader(asset)
+		}
+	}
+
+	tasks := make([]LoadingTask, 0, len(closure))
+	for _, asset := range closure {
+		if task := loader(asset); task != nil {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return rs.CreateBatch(tasks...), nil
+}
+
+func (rs *ResourceSystem) cycleError(stack []Asset, asset Asset) error {
+	start := 0
+	for i, a := range stack {
+		if a == asset {
+			start = i
+			break
+		}
+	}
+
+	cycle := append(append([]Asset{}, stack[start:]...), asset)
+
+	rs.mu.RLock()
+	paths := make([]string, len(cycle))
+	for i, a := range cycle {
+		paths[i] = rs.manifest[a]
+	}
+	rs.mu.RUnlock()
+
+	return &DependencyCycleError{
+		System: rs.name,
+		Cycle:  cycle,
+		Paths:  paths,
+	}
+}
@@ -0,0 +1,161 @@
+package resources_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+// newMapFiles returns two Tiled maps sharing a tileset in another directory, which references its image.
+func newMapFiles() map[string][]byte {
+	resources.RegisterScanner(resources.ScanXMLSources, ".tmx", ".tsx")
+
+	return map[string][]byte{
+		"maps/a.tmx":        []byte(`<map><tileset firstgid="1" source="../shared/sets/x.tsx"/></map>`),
+		"maps/b.tmx":        []byte(`<map><tileset firstgid="1" source="../shared/sets/x.tsx"/></map>`),
+		"shared/sets/x.tsx": []byte(`<tileset><image source="x.png"/></tileset>`),
+		"shared/sets/x.png": []byte("png"),
+		"maps/missing.tmx":  []byte(`<map><tileset firstgid="1" source="../shared/sets/gone.tsx"/></map>`),
+		"cycle/first.tsx":   []byte(`<tileset><tileset source="second.tsx"/></tileset>`),
+		"cycle/second.tsx":  []byte(`<tileset><tileset source="../cycle/first.tsx"/></tileset>`),
+	}
+}
+
+func TestClosureResolvesRelativeReferences(t *testing.T) {
+	rs := resourcestest.NewSystem("deps", newMapFiles())
+
+	level := resourcestest.AssetOf("maps/a.tmx")
+	tileset := resourcestest.AssetOf("shared/sets/x.tsx")
+	image := resourcestest.AssetOf("shared/sets/x.png")
+
+	if dep, err := rs.ResolveReference(level, "../shared/sets/x.tsx"); err != nil || dep != tileset {
+		t.Fatalf("got (%d, %v), want the tileset %d", dep, err, tileset)
+	}
+
+	closure, err := rs.Closure(context.Background(), level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []resources.Asset{image, tileset, level}; !slices.Equal(closure, want) {
+		t.Fatalf("got closure %v, want %v", closure, want)
+	}
+}
+
+func TestClosureReportsCycles(t *testing.T) {
+	rs := resourcestest.NewSystem("deps", newMapFiles())
+
+	first := resourcestest.AssetOf("cycle/first.tsx")
+	second := resourcestest.AssetOf("cycle/second.tsx")
+
+	_, err := rs.Closure(context.Background(), first)
+
+	var cycleErr *resources.DependencyCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("got error %v, want a *DependencyCycleError", err)
+	}
+	if want := []resources.Asset{first, second, first}; !slices.Equal(cycleErr.Cycle, want) {
+		t.Fatalf("got cycle %v, want %v", cycleErr.Cycle, want)
+	}
+	if want := []string{"cycle/first.tsx", "cycle/second.tsx", "cycle/first.tsx"}; !slices.Equal(cycleErr.Paths, want) {
+		t.Fatalf("got cycle paths %v, want %v", cycleErr.Paths, want)
+	}
+}
+
+func TestClosureReportsMissingDependencies(t *testing.T) {
+	rs := resourcestest.NewSystem("deps", newMapFiles())
+
+	level := resourcestest.AssetOf("maps/missing.tmx")
+
+	_, err := rs.Closure(context.Background(), resourcestest.AssetOf("maps/a.tmx"), level)
+
+	var missingErr *resources.MissingDependencyError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("got error %v, want a *MissingDependencyError", err)
+	}
+	if missingErr.From != level || missingErr.FromPath != "maps/missing.tmx" {
+		t.Fatalf("got reference from %d (%s), want %d (maps/missing.tmx)", missingErr.From, missingErr.FromPath, level)
+	}
+	if missingErr.Reference != "../shared/sets/gone.tsx" || missingErr.System != "deps" {
+		t.Fatalf("got reference %q in %s, want ../shared/sets/gone.tsx in deps", missingErr.Reference, missingErr.System)
+	}
+}
+
+func TestCreateDependencyBatchLoadsEachAssetOnce(t *testing.T) {
+	rs := resourcestest.NewSystem("deps", newMapFiles())
+
+	levels := []resources.Asset{resourcestest.AssetOf("maps/a.tmx"), resourcestest.AssetOf("maps/b.tmx")}
+	tileset := resourcestest.AssetOf("shared/sets/x.tsx")
+	image := resourcestest.AssetOf("shared/sets/x.png")
+
+	var (
+		mu     sync.Mutex
+		loaded []resources.Asset
+	)
+	op, err := rs.CreateDependencyBatch(context.Background(), func(asset resources.Asset) resources.LoadingTask {
+		return func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			mu.Lock()
+			defer mu.Unlock()
+			loaded = append(loaded, asset)
+			return nil
+		}
+	}, levels...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := op.Execute(newContext()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []resources.Asset{image, tileset, levels[0], levels[1]}; !slices.Equal(loaded, want) {
+		t.Fatalf("got loads %v, want %v", loaded, want)
+	}
+}
+
+func TestDependenciesDiscardScansOfReloadedAssets(t *testing.T) {
+	scanning := make(chan struct{})
+	resume := make(chan struct{})
+	scans := 0
+	resources.RegisterScanner(func(data []byte) ([]string, error) {
+		scans++
+		if scans == 1 {
+			close(scanning)
+			<-resume
+			return []string{"old.png"}, nil
+		}
+		return []string{"new.png"}, nil
+	}, ".scan")
+
+	files, assets := resourcestest.Files("assets/a.scan", "assets/old.png", "assets/new.png")
+	rs := resourcestest.NewSystem("deps", files)
+
+	result := make(chan []resources.Asset, 1)
+	go func() {
+		deps, err := rs.Dependencies(context.Background(), assets[0])
+		if err != nil {
+			t.Error(err)
+		}
+		result <- deps
+	}()
+
+	// The asset changes while its previous contents are being scanned.
+	<-scanning
+	if err := rs.Reload(context.Background(), assets[0]); err != nil {
+		t.Fatal(err)
+	}
+	close(resume)
+	<-result
+
+	deps, err := rs.Dependencies(context.Background(), assets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []resources.Asset{assets[2]}; !slices.Equal(deps, want) {
+		t.Fatalf("got dependencies %v, want the ones of the changed asset %v", deps, want)
+	}
+}
@@ -0,0 +1,67 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ============================== Digests ==============================
+
+// AssetDigest records the expected size and content hash of an asset file.
+type AssetDigest struct {
+	Size   int64  // Size of the file in bytes
+	SHA256 string // Hex-encoded SHA-256 hash of the file contents
+}
+
+// NewAssetDigest computes the digest of the given file contents.
+func NewAssetDigest(data []byte) AssetDigest {
+	sum := sha256.Sum256(data)
+	return AssetDigest{
+		Size:   int64(len(data)),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+}
+
+func (d AssetDigest) String() string {
+	return fmt.Sprintf("sha256:%s (%d bytes)", d.SHA256, d.Size)
+}
+
+// AssetDigests maps Asset identifiers to the digests of their files.
+type AssetDigests map[Asset]AssetDigest
+
+// ErrAssetCorrupt is returned when the contents of an asset file do not match its recorded digest.
+type ErrAssetCorrupt struct {
+	System   string      // Name of the resource system the asset belongs to
+	Asset    Asset       // The corrupt asset
+	Path     string      // Path of the asset file
+	Expected AssetDigest // Digest recorded in the manifest
+	Actual   AssetDigest // Digest of the file that was read
+}
+
+func (e *ErrAssetCorrupt) Error() string {
+	return fmt.Sprintf("asset 0x%x (%s) in resource system %s is corrupt: expected %s, got %s", e.Asset, e.Path, e.System, e.Expected, e.Actual)
+}
+
+// SetVerify enables or disables verification of asset contents against their recorded digests.
+//
+// When enabled, reading an asset whose contents do not match its digest returns an *ErrAssetCorrupt.
+// Assets without a recorded digest are never verified, nor are files served by a layer above the base
+// layer, as digests describe the base files that patches and mods override. Files returned by Open are
+// verified in full before Open returns, so that decoders may seek within them or stop reading early.
+func (rs *ResourceSystem) SetVerify(enabled bool) {
+	rs.verify.Store(enabled)
+}
+
+// expectedDigest returns the recorded digest of an asset, if verification is enabled and one exists.
+func (rs *ResourceSystem) expectedDigest(asset Asset) (AssetDigest, bool) {
+	if !rs.verify.Load() {
+		return AssetDigest{}, false
+	}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	expected, exists := rs.options.Digests[asset]
+	return expected, exists
+}
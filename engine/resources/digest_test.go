@@ -0,0 +1,84 @@
+package resources_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestVerifySkipsOverriddenFiles(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("verify", files)
+	rs.SetVerify(true)
+
+	// Corrupt the base file of the second asset, and override the first one with a patch.
+	base := make(map[string][]byte, len(files))
+	patch := make(map[string][]byte)
+	for assetPath, data := range files {
+		base[assetPath] = data
+	}
+	for assetPath := range files {
+		if resourcestest.AssetOf(assetPath) == assets[0] {
+			patch[assetPath] = []byte("patched")
+		} else {
+			base[assetPath] = []byte("corrupt")
+		}
+	}
+	rs.SetFileSystem(resourcestest.NewFS(base))
+	rs.PushLayer("patch", resourcestest.NewFS(patch))
+
+	data, err := rs.ReadBytes(assets[0])
+	if err != nil {
+		t.Fatalf("overridden file failed verification: %v", err)
+	}
+	if string(data) != "patched" {
+		t.Fatalf("got %q, want the patched file", data)
+	}
+
+	var corrupt *resources.ErrAssetCorrupt
+	if _, err := rs.ReadBytes(assets[1]); !errors.As(err, &corrupt) {
+		t.Fatalf("got error %v, want ErrAssetCorrupt for the corrupt base file", err)
+	}
+}
+
+func TestOpenVerifiesFilesBeforeSeeking(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("verify", files)
+	rs.SetVerify(true)
+
+	corrupted := make(map[string][]byte, len(files))
+	for assetPath, data := range files {
+		corrupted[assetPath] = data
+	}
+	corrupted["assets/file01.bin"] = []byte("assets/file01.bim")
+	rs.SetFileSystem(resourcestest.NewFS(corrupted))
+
+	// A corrupt file is reported even if the decoder would only seek within it or read part of it.
+	var corrupt *resources.ErrAssetCorrupt
+	if _, err := rs.Open(assets[1]); !errors.As(err, &corrupt) {
+		t.Fatalf("got error %v, want ErrAssetCorrupt", err)
+	}
+
+	file, err := rs.Open(assets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if offset, err := file.Seek(0, io.SeekCurrent); err != nil || offset != 0 {
+		t.Fatalf("got offset (%d, %v) after verification, want 0", offset, err)
+	}
+	if _, err := file.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(file, head); err != nil {
+		t.Fatal(err)
+	}
+	if string(head) != "file" {
+		t.Fatalf("got %q, want file", head)
+	}
+}
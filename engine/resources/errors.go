@@ -0,0 +1,65 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ============================== Errors ==============================
+
+var (
+	// ErrUnknownAsset is returned when an asset does not exist in the manifest of a resource system.
+	ErrUnknownAsset = errors.New("asset does not exist in resource system")
+
+	// ErrNoFileSystem is returned when reading from a resource system that has no filesystem.
+	ErrNoFileSystem = errors.New("resource system has no associated filesystem")
+
+	// ErrBatchHoldsLock is returned when a batch attempts to acquire an asset lock while already holding one.
+	ErrBatchHoldsLock = errors.New("resource batch already holds an asset lock")
+
+	// ErrDecode is returned when the contents of an asset cannot be decoded.
+	ErrDecode = errors.New("asset could not be decoded")
+)
+
+// AssetError records an error that occurred while operating on an asset of a resource system.
+//
+// AssetError wraps the underlying error, so errors.Is can be used to test for ErrUnknownAsset,
+// ErrNoFileSystem, ErrDecode or fs.ErrNotExist when the asset file is missing.
+type AssetError struct {
+	Op     string // The operation that failed, e.g. "open", "read", "lock" or "decode"
+	System string // Name of the resource system
+	Asset  Asset  // The asset being operated on
+	Path   string // Path of the asset file, if known
+	Err    error  // The underlying error
+}
+
+func (e *AssetError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("failed to %s asset 0x%x in resource system %s: %v", e.Op, e.Asset, e.System, e.Err)
+	}
+	return fmt.Sprintf("failed to %s asset 0x%x (%s) in resource system %s: %v", e.Op, e.Asset, e.Path, e.System, e.Err)
+}
+
+func (e *AssetError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError wraps an error returned by a decoder for the asset, so that it matches ErrDecode.
+func (rs *ResourceSystem) DecodeError(asset Asset, err error) error {
+	return rs.assetError("decode", asset, fmt.Errorf("%w: %w", ErrDecode, err))
+}
+
+// assetError creates an AssetError for the asset, filling in its manifest path.
+func (rs *ResourceSystem) assetError(op string, asset Asset, err error) *AssetError {
+	rs.mu.RLock()
+	path := rs.manifest[asset]
+	rs.mu.RUnlock()
+
+	return &AssetError{
+		Op:     op,
+		System: rs.name,
+		Asset:  asset,
+		Path:   path,
+		Err:    err,
+	}
+}
@@ -0,0 +1,147 @@
+package resources_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestReadErrorsIdentifyAsset(t *testing.T) {
+	files, assets := newFiles(2)
+	missing := assets[1]
+	delete(files, "assets/file01.bin")
+
+	withFiles := resources.NewResourceSystem("errors", resourcestest.NewManifest(files).Assets, resources.ResourceSystemOptions{})
+	withFiles.SetFileSystem(resourcestest.NewFS(files))
+
+	manifest := resourcestest.NewManifest(files).Assets
+	manifest[missing] = "assets/file01.bin"
+	withMissing := resources.NewResourceSystem("errors", manifest, resources.ResourceSystemOptions{})
+	withMissing.SetFileSystem(resourcestest.NewFS(files))
+
+	withoutFiles := resources.NewResourceSystem("errors", manifest, resources.ResourceSystemOptions{})
+
+	read := func(rs *resources.ResourceSystem, asset resources.Asset) func() error {
+		return func() error {
+			_, err := rs.ReadBytes(asset)
+			return err
+		}
+	}
+
+	tests := map[string]struct {
+		op    func() error
+		asset resources.Asset
+		path  string
+		err   error
+	}{
+		"unknown asset": {op: read(withFiles, missing), asset: missing, err: resources.ErrUnknownAsset},
+		"no filesystem": {op: read(withoutFiles, assets[0]), asset: assets[0], path: "assets/file00.bin", err: resources.ErrNoFileSystem},
+		"missing file":  {op: read(withMissing, missing), asset: missing, path: "assets/file01.bin", err: fs.ErrNotExist},
+		"decode": {
+			op: func() error {
+				return withFiles.DecodeError(assets[0], errors.New("bad header"))
+			},
+			asset: assets[0],
+			path:  "assets/file00.bin",
+			err:   resources.ErrDecode,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.op()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			var assetErr *resources.AssetError
+			if !errors.As(err, &assetErr) {
+				t.Fatalf("got error %v, want an *AssetError", err)
+			}
+			if assetErr.System != "errors" || assetErr.Asset != test.asset || assetErr.Path != test.path {
+				t.Fatalf("got asset 0x%x (%q) in %s, want 0x%x (%q) in errors", assetErr.Asset, assetErr.Path, assetErr.System, test.asset, test.path)
+			}
+		})
+	}
+}
+
+func TestExecuteAllContinuesPastFailures(t *testing.T) {
+	files, assets := newFiles(3)
+	rs := resourcestest.NewSystem("errors", files)
+
+	unknown := resources.Asset(0)
+	read := func(asset resources.Asset) resources.LoadingTask {
+		return func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			_, err := rs.ReadBytesContext(ctx, asset)
+			return err
+		}
+	}
+	failure := errors.New("task failed")
+
+	ran := 0
+	op := rs.CreateBatch()
+	for _, task := range []resources.LoadingTask{
+		read(assets[0]),
+		read(unknown),
+		read(assets[1]),
+		func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error { return failure },
+		read(assets[2]),
+	} {
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			ran++
+			return task(ctx, rs, batchID)
+		})
+	}
+
+	report := op.ExecuteAll(newContext())
+
+	if ran != 5 || report.Tasks != 5 {
+		t.Fatalf("ran %d of %d tasks, want 5", ran, report.Tasks)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].Task != 1 || report.Failures[1].Task != 3 {
+		t.Fatalf("got failures %v, want tasks 1 and 3", report.Failures)
+	}
+	if err := report.Err(); !errors.Is(err, resources.ErrUnknownAsset) || !errors.Is(err, failure) {
+		t.Fatalf("got error %v, want ErrUnknownAsset and the task failure", err)
+	}
+
+	assetErrs := report.AssetErrors()
+	if len(assetErrs) != 1 || assetErrs[0].Asset != unknown || assetErrs[0].System != "errors" {
+		t.Fatalf("got asset errors %v, want one for the unknown asset", assetErrs)
+	}
+}
+
+func TestExecuteAllRecordsCancelledTasks(t *testing.T) {
+	rs := resourcestest.NewSystem("errors", map[string][]byte{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	op := rs.CreateBatch()
+	op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+		cancel()
+		return nil
+	})
+	for range 2 {
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			t.Error("task ran after the context was cancelled")
+			return nil
+		})
+	}
+
+	report := op.ExecuteAll(newContext().WithContext(ctx))
+
+	if len(report.Failures) != 2 || report.Failures[0].Task != 1 || report.Failures[1].Task != 2 {
+		t.Fatalf("got failures %v, want tasks 1 and 2", report.Failures)
+	}
+	for _, failure := range report.Failures {
+		if !errors.Is(failure.Err, context.Canceled) {
+			t.Fatalf("got error %v for task %d, want context.Canceled", failure.Err, failure.Task)
+		}
+	}
+}
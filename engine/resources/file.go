@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+)
+
+// ============================== Files ==============================
+
+// AssetFile is an open asset that supports streaming and seeking.
+type AssetFile interface {
+	fs.File
+	io.Seeker
+}
+
+// newAssetFile adapts a file opened from a layer into an AssetFile whose reads honour the context.
+//
+// Files that do not support seeking are read into memory.
+func newAssetFile(ctx context.Context, file fs.File) (AssetFile, error) {
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		return &assetFile{File: file, ctx: ctx, content: seeker}, nil
+	}
+
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return nil, err
+	}
+
+	return &assetFile{File: file, ctx: ctx, content: bytes.NewReader(data)}, nil
+}
+
+type assetFile struct {
+	fs.File
+	ctx     context.Context
+	content io.ReadSeeker // Either the file itself or its contents read into memory
+}
+
+func (f *assetFile) Read(p []byte) (int, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return f.content.Read(p)
+}
+
+func (f *assetFile) Seek(offset int64, whence int) (int64, error) {
+	return f.content.Seek(offset, whence)
+}
+
+// verifyingFile checks the contents of an AssetFile against a recorded digest as it is read.
+//
+// The digest is checked once the file has been read to the end, in which case the final read returns the
+// corruption error instead of io.EOF. It is only used for files read sequentially to the end, such as by
+// ReadBytes; files that may be seeked or partially read are verified up front by verifyAssetFile.
+type verifyingFile struct {
+	AssetFile
+	hash    hash.Hash
+	size    int64
+	corrupt *ErrAssetCorrupt
+}
+
+func newVerifyingFile(file AssetFile, corrupt *ErrAssetCorrupt) AssetFile {
+	return &verifyingFile{
+		AssetFile: file,
+		hash:      sha256.New(),
+		corrupt:   corrupt,
+	}
+}
+
+func (f *verifyingFile) Read(p []byte) (int, error) {
+	n, err := f.AssetFile.Read(p)
+	if f.hash == nil {
+		return n, err
+	}
+
+	f.hash.Write(p[:n])
+	f.size += int64(n)
+
+	if errors.Is(err, io.EOF) {
+		actual := AssetDigest{Size: f.size, SHA256: hex.EncodeToString(f.hash.Sum(nil))}
+		f.hash = nil
+
+		if actual != f.corrupt.Expected {
+			f.corrupt.Actual = actual
+			return n, f.corrupt
+		}
+	}
+
+	return n, err
+}
+
+func (f *verifyingFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("resources: cannot seek an asset file verified as it is read")
+}
+
+// verifyAssetFile checks the whole contents of an AssetFile against a recorded digest, and rewinds the file
+// to its start if they match.
+func verifyAssetFile(file AssetFile, corrupt *ErrAssetCorrupt) error {
+	verifier := &verifyingFile{AssetFile: file, hash: sha256.New(), corrupt: corrupt}
+	if _, err := io.Copy(io.Discard, verifier); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return nil
+}
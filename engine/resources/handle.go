@@ -0,0 +1,338 @@
+package resources
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ============================== Handles ==============================
+
+// Handle is a reference-counted reference to a value loaded for an Asset.
+//
+// Each Handle must be released exactly once by calling Release(). Calling Release() multiple times
+// will panic. The value returned by Value() must not be used after the Handle is released.
+type Handle[T any] struct {
+	noCopy
+
+	table *HandleTable[T]
+	entry *handleEntry[T] // Guarded by the table mutex, nil once the handle is released
+	asset Asset
+	site  string // Call sites where the handle was acquired, used for leak reports
+}
+
+// Asset returns the asset the Handle refers to.
+func (h *Handle[T]) Asset() Asset {
+	return h.asset
+}
+
+// Value returns the value the Handle refers to.
+func (h *Handle[T]) Value() T {
+	h.table.mu.Lock()
+	defer h.table.mu.Unlock()
+	return h.entry.value
+}
+
+// Release drops the reference held by the Handle.
+//
+// When the last reference to an asset is dropped, the underlying value is released by the HandleTable.
+func (h *Handle[T]) Release() {
+	h.table.handleReleased(h)
+}
+
+// HandleLeak describes an asset that still has live handles.
+type HandleLeak struct {
+	Asset   Asset    // The asset that is still referenced
+	Handles int      // Number of handles that have not been released
+	Sites   []string // Call sites where the live handles were acquired
+}
+
+func (l HandleLeak) String() string {
+	return fmt.Sprintf("asset 0x%x has %d live handle(s) acquired at %v", l.Asset, l.Handles, l.Sites)
+}
+
+// HandleTable stores values keyed by Asset and tracks how many holders reference each of them.
+//
+// The table holds its own reference to every stored value until Delete is called. A value is only
+// released once the table and every Handle acquired for it have dropped their references.
+//
+// HandleTable is safe for concurrent use by multiple goroutines.
+type HandleTable[T any] struct {
+	release func(asset Asset, value T)
+	entries map[Asset]*handleEntry[T]
+	mu      sync.Mutex
+}
+
+type handleEntry[T any] struct {
+	asset   Asset
+	value   T
+	owned   bool                    // Whether the table still holds its own reference
+	live    map[*Handle[T]]struct{} // Handles that have not been released
+	retired []*retiredValue[T]      // Replaced values still held by the handles that were live at the time
+}
+
+// retiredValue is a value replaced while handles to it were live. It is released once all of them are released.
+type retiredValue[T any] struct {
+	value   T
+	holders map[*Handle[T]]struct{}
+}
+
+func (e *handleEntry[T]) refs() int {
+	refs := len(e.live)
+	if e.owned {
+		refs++
+	}
+	return refs
+}
+
+// NewHandleTable creates a new HandleTable.
+//
+// The release function is called whenever the last reference to a value is dropped, including the
+// references to a value that was replaced. It may be nil if values do not need to be released explicitly.
+func NewHandleTable[T any](release func(asset Asset, value T)) *HandleTable[T] {
+	return &HandleTable[T]{
+		release: release,
+		entries: make(map[Asset]*handleEntry[T]),
+	}
+}
+
+// Get returns the value stored for the asset without acquiring a reference to it.
+func (ht *HandleTable[T]) Get(asset Asset) (T, bool) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	if entry, exists := ht.entries[asset]; exists {
+		return entry.value, true
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Store stores the value for the asset, with the table holding a reference to it.
+//
+// If a value is already stored for the asset, it is replaced in place so that existing handles
+// observe the new value. Holders may still be using the previous value they obtained from Value(), so
+// it is only released once every handle that was live when it was replaced has been released. Storing
+// the value that is already stored for the asset only restores the reference held by the table.
+func (ht *HandleTable[T]) Store(asset Asset, value T) {
+	ht.mu.Lock()
+
+	entry, exists := ht.entries[asset]
+	if !exists {
+		ht.entries[asset] = &handleEntry[T]{
+			asset: asset,
+			value: value,
+			owned: true,
+			live:  make(map[*Handle[T]]struct{}),
+		}
+		ht.mu.Unlock()
+		return
+	}
+
+	previous := entry.value
+	entry.owned = true
+	if sameValue(previous, value) {
+		ht.mu.Unlock()
+		return
+	}
+	entry.value = value
+
+	if len(entry.live) > 0 {
+		entry.retired = append(entry.retired, &retiredValue[T]{
+			value:   previous,
+			holders: maps.Clone(entry.live),
+		})
+		ht.mu.Unlock()
+		return
+	}
+	ht.mu.Unlock()
+
+	ht.releaseValue(asset, previous)
+}
+
+// Acquire returns a new Handle to the value stored for the asset.
+//
+// If no value is stored for the asset, false is returned. The returned Handle must be released by
+// calling Release() exactly once when done.
+func (ht *HandleTable[T]) Acquire(asset Asset) (*Handle[T], bool) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	entry, exists := ht.entries[asset]
+	if !exists {
+		return nil, false
+	}
+
+	handle := &Handle[T]{
+		table: ht,
+		entry: entry,
+		asset: asset,
+		site:  callerSites(3),
+	}
+	entry.live[handle] = struct{}{}
+
+	return handle, true
+}
+
+// Delete drops the reference the table holds to the value stored for the asset.
+//
+// If handles to the value are still alive, the value is released once the last of them is released.
+func (ht *HandleTable[T]) Delete(asset Asset) {
+	ht.mu.Lock()
+
+	entry, exists := ht.entries[asset]
+	if !exists || !entry.owned {
+		ht.mu.Unlock()
+		return
+	}
+
+	entry.owned = false
+	if entry.refs() > 0 {
+		ht.mu.Unlock()
+		return
+	}
+
+	delete(ht.entries, asset)
+	ht.mu.Unlock()
+
+	ht.releaseValue(asset, entry.value)
+}
+
+// Range calls fn for each value stored in the table, stopping early if fn returns false.
+//
+// The table is locked while fn runs, so fn must not call other methods on the table.
+func (ht *HandleTable[T]) Range(fn func(asset Asset, value T) bool) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	for asset, entry := range ht.entries {
+		if !fn(asset, entry.value) {
+			return
+		}
+	}
+}
+
+// Refs returns the number of references held to the value stored for the asset, including the
+// reference held by the table itself.
+func (ht *HandleTable[T]) Refs(asset Asset) int {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	if entry, exists := ht.entries[asset]; exists {
+		return entry.refs()
+	}
+	return 0
+}
+
+// Leaks reports every asset that still has live handles, sorted by asset.
+//
+// Leaks is typically called at shutdown, once all holders are expected to have released their handles.
+func (ht *HandleTable[T]) Leaks() []HandleLeak {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	leaks := make([]HandleLeak, 0)
+	for asset, entry := range ht.entries {
+		if len(entry.live) == 0 {
+			continue
+		}
+
+		sites := make([]string, 0, len(entry.live))
+		for handle := range entry.live {
+			sites = append(sites, handle.site)
+		}
+		slices.Sort(sites)
+
+		leaks = append(leaks, HandleLeak{
+			Asset:   asset,
+			Handles: len(entry.live),
+			Sites:   sites,
+		})
+	}
+
+	slices.SortFunc(leaks, func(a, b HandleLeak) int {
+		return cmp.Compare(a.Asset, b.Asset)
+	})
+
+	return leaks
+}
+
+// handleReleased is an internal method called when a Handle is released.
+//
+// It releases the values that were only held by the handle: retired values it was the last holder of, and
+// the current value if no other reference to it remains.
+func (ht *HandleTable[T]) handleReleased(handle *Handle[T]) {
+	ht.mu.Lock()
+
+	entry := handle.entry
+	if entry == nil {
+		ht.mu.Unlock()
+		panic("Handle released multiple times. DO NOT release a Handle more than once.")
+	}
+	handle.entry = nil
+	delete(entry.live, handle)
+
+	released := make([]T, 0)
+	entry.retired = slices.DeleteFunc(entry.retired, func(retired *retiredValue[T]) bool {
+		delete(retired.holders, handle)
+		if len(retired.holders) > 0 {
+			return false
+		}
+		released = append(released, retired.value)
+		return true
+	})
+
+	if entry.refs() == 0 && ht.entries[entry.asset] == entry {
+		delete(ht.entries, entry.asset)
+		released = append(released, entry.value)
+	}
+	ht.mu.Unlock()
+
+	for _, value := range released {
+		ht.releaseValue(entry.asset, value)
+	}
+}
+
+func (ht *HandleTable[T]) releaseValue(asset Asset, value T) {
+	if ht.release != nil {
+		ht.release(asset, value)
+	}
+}
+
+// sameValue reports whether a and b are the same value. Values of types that are not comparable, such as
+// slices, are never considered the same.
+func sameValue[T any](a, b T) bool {
+	va, vb := any(a), any(b)
+	if va != nil && !reflect.ValueOf(va).Comparable() {
+		return false
+	}
+	return va == vb
+}
+
+// callerSites returns the innermost call sites above the caller of the caller of callerSites,
+// formatted as a chain from the innermost to the outermost frame.
+func callerSites(depth int) string {
+	pcs := make([]uintptr, depth)
+	n := runtime.Callers(3, pcs)
+	if n == 0 {
+		return "unknown"
+	}
+
+	sites := make([]string, 0, n)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		sites = append(sites, fmt.Sprintf("%s:%d", frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+
+	return strings.Join(sites, " <- ")
+}
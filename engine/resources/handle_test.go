@@ -0,0 +1,141 @@
+package resources_test
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+type value struct{ name string }
+
+// releases records the values released by a HandleTable.
+type releases struct {
+	values []*value
+	mu     sync.Mutex
+}
+
+func (r *releases) release(asset resources.Asset, v *value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values = append(r.values, v)
+}
+
+func (r *releases) released(v *value) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Contains(r.values, v)
+}
+
+func TestHandleTableStoreSameValue(t *testing.T) {
+	r := &releases{}
+	table := resources.NewHandleTable(r.release)
+
+	v := &value{"a"}
+	table.Store(1, v)
+	table.Store(1, v)
+
+	if r.released(v) {
+		t.Fatal("storing the stored value released it")
+	}
+	if got, _ := table.Get(1); got != v {
+		t.Fatalf("got %v, want %v", got, v)
+	}
+
+	table.Delete(1)
+	if !r.released(v) {
+		t.Fatal("deleting the value did not release it")
+	}
+}
+
+func TestHandleTableStoreKeepsReplacedValueForLiveHandles(t *testing.T) {
+	r := &releases{}
+	table := resources.NewHandleTable(r.release)
+
+	old, replacement := &value{"old"}, &value{"new"}
+	table.Store(1, old)
+
+	handle, _ := table.Acquire(1)
+	held := handle.Value()
+
+	table.Store(1, replacement)
+	if handle.Value() != replacement {
+		t.Fatal("handle does not observe the replacement value")
+	}
+	if r.released(held) {
+		t.Fatal("replaced value released while a handle that saw it is live")
+	}
+
+	// Handles acquired after the replacement do not hold the replaced value.
+	later, _ := table.Acquire(1)
+
+	handle.Release()
+	if !r.released(old) {
+		t.Fatal("replaced value not released once its last handle was released")
+	}
+
+	later.Release()
+	table.Delete(1)
+	if !r.released(replacement) {
+		t.Fatal("replacement value not released")
+	}
+}
+
+func TestHandleTableStoreReleasesUnheldValue(t *testing.T) {
+	r := &releases{}
+	table := resources.NewHandleTable(r.release)
+
+	old := &value{"old"}
+	table.Store(1, old)
+	table.Store(1, &value{"new"})
+
+	if !r.released(old) {
+		t.Fatal("replaced value without handles not released")
+	}
+}
+
+func TestHandleTableStoreUncomparableValue(t *testing.T) {
+	released := 0
+	table := resources.NewHandleTable(func(asset resources.Asset, v []byte) {
+		released++
+	})
+
+	data := []byte("data")
+	table.Store(1, data)
+	table.Store(1, data)
+
+	if released != 1 {
+		t.Fatalf("released %d values, want 1", released)
+	}
+}
+
+func TestHandleConcurrentRelease(t *testing.T) {
+	for range 100 {
+		table := resources.NewHandleTable[*value](nil)
+		table.Store(1, &value{"a"})
+		handle, _ := table.Acquire(1)
+
+		panics := atomic.Int64{}
+		wg := sync.WaitGroup{}
+		for range 2 {
+			wg.Go(func() {
+				defer func() {
+					if recover() != nil {
+						panics.Add(1)
+					}
+				}()
+				handle.Release()
+			})
+		}
+		wg.Wait()
+
+		if panics.Load() != 1 {
+			t.Fatalf("got %d panics releasing a handle twice, want 1", panics.Load())
+		}
+		if refs := table.Refs(1); refs != 1 {
+			t.Fatalf("got %d references after releasing the handle, want 1", refs)
+		}
+	}
+}
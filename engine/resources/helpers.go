@@ -1,6 +1,10 @@
 package resources
 
-import "strings"
+import (
+	"context"
+	"io"
+	"strings"
+)
 
 // noCopy may be embedded into structs which must not be copied
 type noCopy struct{}
@@ -11,6 +15,55 @@ func (*noCopy) Lock() {}
 // Unlock is a no-op method to prevent copying of structs embedding noCopy.
 func (*noCopy) Unlock() {}
 
+// assetMutex is a mutual exclusion lock whose Lock operation can be abandoned when a context is cancelled.
+type assetMutex chan struct{}
+
+func newAssetMutex() assetMutex {
+	return make(assetMutex, 1)
+}
+
+// Lock blocks until the mutex is acquired or the context is cancelled.
+func (m assetMutex) Lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case m <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock acquires the mutex if it is available, reporting whether it was acquired.
+func (m assetMutex) TryLock() bool {
+	select {
+	case m <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock releases the mutex.
+func (m assetMutex) Unlock() {
+	<-m
+}
+
+// contextReader is an io.Reader that stops reading once its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
 func trimAssetPathRoot(path string) string {
 	parts := strings.SplitN(path, "/", 2)
 	if len(parts) < 2 {
@@ -0,0 +1,187 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+)
+
+// BaseLayer is the name of the layer created by SetFileSystem.
+const BaseLayer = "base"
+
+// ============================== Layers ==============================
+
+// AssetFS is implemented by filesystems that can look up assets directly by their identifier, such as
+// pack archives. A ResourceSystem reads assets from an AssetFS without translating them to a path.
+type AssetFS interface {
+	fs.FS
+
+	// OpenAsset opens the file for the asset.
+	OpenAsset(asset Asset) (fs.File, error)
+
+	// StatAsset returns file information for the asset. It returns an error wrapping fs.ErrNotExist
+	// if the filesystem does not contain the asset.
+	StatAsset(asset Asset) (fs.FileInfo, error)
+}
+
+// Layer is a named filesystem within the layer stack of a ResourceSystem.
+//
+// Layers allow patches and mods to override individual assets without regenerating the manifest:
+// an asset is read from the highest-priority layer that contains its path.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// SetLayers replaces the layer stack of the ResourceSystem.
+//
+// Layers are given in increasing order of priority: the first layer is the base, and each following
+// layer overrides the assets of the layers before it.
+func (rs *ResourceSystem) SetLayers(layers ...Layer) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.layers = slices.Clone(layers)
+	rs.revision++
+	clear(rs.served)
+}
+
+// PushLayer adds a layer on top of the layer stack, giving it the highest priority.
+//
+// Layer names identify layers in RemoveLayer and ServedBy, so they should be unique within the stack.
+func (rs *ResourceSystem) PushLayer(name string, fs fs.FS) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.layers = append(rs.layers, Layer{Name: name, FS: fs})
+	rs.revision++
+
+	// The new layer may override any asset.
+	clear(rs.served)
+}
+
+// RemoveLayer removes the named layer from the layer stack, reporting whether it was present. If several
+// layers share the name, the one with the highest priority is removed.
+func (rs *ResourceSystem) RemoveLayer(name string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	index := -1
+	for i, layer := range slices.Backward(rs.layers) {
+		if layer.Name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false
+	}
+
+	rs.layers = slices.Delete(rs.layers, index, index+1)
+	rs.revision++
+
+	// Only the assets served by the removed layer are now served by another one.
+	maps.DeleteFunc(rs.served, func(asset Asset, served string) bool {
+		return served == name
+	})
+	return true
+}
+
+// Layers returns the layer stack of the ResourceSystem in increasing order of priority.
+func (rs *ResourceSystem) Layers() []Layer {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return slices.Clone(rs.layers)
+}
+
+// LayerOf returns the layer an asset would currently be read from.
+func (rs *ResourceSystem) LayerOf(asset Asset) (Layer, error) {
+	layer, _, err := rs.resolve(asset)
+	return layer, err
+}
+
+// ServedBy returns the name of the layer the asset was last read from, if it has been read.
+func (rs *ResourceSystem) ServedBy(asset Asset) (string, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	name, exists := rs.served[asset]
+	return name, exists
+}
+
+// fileRef identifies the file that provides an asset: the asset's own file, or its selected variant.
+type fileRef struct {
+	id         Asset  // Identifier of the file, used to look it up in an AssetFS
+	path       string // Path of the file within the layers
+	overridden bool   // Whether the file is served by a layer above the base layer
+}
+
+// resolve returns the layer and file used to read the specified asset.
+//
+// The layer stack is searched from the highest priority down, and the first layer that contains the
+// asset file is returned. When there is a single layer, it is returned without being searched.
+func (rs *ResourceSystem) resolve(asset Asset) (Layer, fileRef, error) {
+	rs.mu.RLock()
+	path, exists := rs.manifest[asset]
+	ref := fileRef{id: asset, path: path}
+	if variant, selected := rs.selected[asset]; selected {
+		ref = fileRef{id: variant.ID, path: variant.Path}
+	}
+	layers := rs.layers
+	rs.mu.RUnlock()
+
+	if !exists {
+		return Layer{}, fileRef{}, rs.assetError("open", asset, ErrUnknownAsset)
+	}
+
+	if len(layers) == 0 {
+		return Layer{}, fileRef{}, rs.assetError("open", asset, ErrNoFileSystem)
+	}
+
+	if rs.options.TrimRoot {
+		ref.path = trimAssetPathRoot(ref.path)
+	}
+
+	if len(layers) == 1 {
+		return layers[0], ref, nil
+	}
+
+	for i, layer := range slices.Backward(layers) {
+		_, err := layer.stat(ref)
+		if err == nil {
+			ref.overridden = i > 0
+			return layer, ref, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return Layer{}, fileRef{}, rs.assetError("stat", asset, fmt.Errorf("layer %s: %w", layer.Name, err))
+		}
+	}
+
+	return Layer{}, fileRef{}, rs.assetError("open", asset, fs.ErrNotExist)
+}
+
+// open opens the file from the layer, looking it up by identifier if the layer supports it.
+func (l Layer) open(ref fileRef) (fs.File, error) {
+	if afs, ok := l.FS.(AssetFS); ok {
+		return afs.OpenAsset(ref.id)
+	}
+	return l.FS.Open(ref.path)
+}
+
+// stat returns file information for the file from the layer, looking it up by identifier if the layer
+// supports it.
+func (l Layer) stat(ref fileRef) (fs.FileInfo, error) {
+	if afs, ok := l.FS.(AssetFS); ok {
+		return afs.StatAsset(ref.id)
+	}
+	return fs.Stat(l.FS, ref.path)
+}
+
+// assetServed records the layer an asset was read from.
+func (rs *ResourceSystem) assetServed(asset Asset, layer Layer) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.served[asset] = layer.Name
+}
@@ -0,0 +1,73 @@
+package resources_test
+
+import (
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestServedByFollowsLayerChanges(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("layers", files)
+
+	read := func(asset resources.Asset) {
+		t.Helper()
+		if _, err := rs.ReadBytes(asset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	servedBy := func(asset resources.Asset) string {
+		name, _ := rs.ServedBy(asset)
+		return name
+	}
+
+	read(assets[0])
+	read(assets[1])
+
+	overrides, _ := newFiles(1)
+	rs.PushLayer("mods", resourcestest.NewFS(overrides))
+	if name := servedBy(assets[0]); name != "" {
+		t.Fatalf("asset reported as served by %q after a layer was pushed", name)
+	}
+
+	read(assets[0])
+	read(assets[1])
+	if servedBy(assets[0]) != "mods" || servedBy(assets[1]) != resources.BaseLayer {
+		t.Fatalf("got layers %q and %q, want mods and base", servedBy(assets[0]), servedBy(assets[1]))
+	}
+
+	if !rs.RemoveLayer("mods") {
+		t.Fatal("mods layer not removed")
+	}
+	if name := servedBy(assets[0]); name != "" {
+		t.Fatalf("asset reported as served by %q after its layer was removed", name)
+	}
+	if name := servedBy(assets[1]); name != resources.BaseLayer {
+		t.Fatalf("asset served by the base layer reported as served by %q", name)
+	}
+}
+
+func TestRemoveLayerRemovesHighestPriority(t *testing.T) {
+	files, assets := newFiles(1)
+	rs := resourcestest.NewSystem("layers", files)
+
+	lower := map[string][]byte{}
+	upper := map[string][]byte{}
+	for assetPath := range files {
+		lower[assetPath] = []byte("lower")
+		upper[assetPath] = []byte("upper")
+	}
+	rs.PushLayer("mods", resourcestest.NewFS(lower))
+	rs.PushLayer("mods", resourcestest.NewFS(upper))
+
+	rs.RemoveLayer("mods")
+
+	data, err := rs.ReadBytes(assets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "lower" {
+		t.Fatalf("got %q, want the lower mods layer to remain", data)
+	}
+}
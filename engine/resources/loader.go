@@ -1,6 +1,13 @@
 package resources
 
-import "github.com/adm87/flinch/engine/flinch"
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/adm87/flinch/engine/flinch"
+)
 
 // LoadingTask is a function type that defines a single loading task within a loading operation.
 type LoadingTask func(ctx *flinch.Context, rs *ResourceSystem, batchID uint64) error
@@ -18,11 +25,292 @@ func (lo *LoadingOperation) AddTask(task LoadingTask) {
 }
 
 // Execute performs all loading tasks within the LoadingOperation.
-func (lo *LoadingOperation) Execute(ctx *flinch.Context) error {
+//
+// The context is checked between tasks. If it is cancelled, no further tasks are started
+// and ctx.Err() is returned.
+func (lo *LoadingOperation) Execute(ctx *flinch.Context) (err error) {
+	collector := lo.rs.collectBatch(lo.batchID)
+	defer func() {
+		failed := 0
+		if err != nil {
+			failed = 1
+		}
+		collector.finish(len(lo.tasks), failed)
+	}()
+
 	for _, task := range lo.tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := task(ctx, lo.rs, lo.batchID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// ExecuteAll performs every loading task within the LoadingOperation, continuing past failures, and
+// returns a report of the tasks that failed.
+//
+// The context is checked between tasks. If it is cancelled, the remaining tasks are recorded as failed
+// with ctx.Err().
+func (lo *LoadingOperation) ExecuteAll(ctx *flinch.Context) *LoadReport {
+	report := &LoadReport{
+		Tasks: len(lo.tasks),
+	}
+
+	collector := lo.rs.collectBatch(lo.batchID)
+	defer func() {
+		collector.finish(report.Tasks, len(report.Failures))
+	}()
+
+	for i, task := range lo.tasks {
+		err := ctx.Err()
+		if err == nil {
+			err = task(ctx, lo.rs, lo.batchID)
+		}
+		if err != nil {
+			report.Failures = append(report.Failures, TaskFailure{Task: i, Err: err})
+		}
+	}
+
+	return report
+}
+
+// ExecuteParallel performs all loading tasks within the LoadingOperation using a bounded pool of workers.
+//
+// Each worker is assigned its own batch ID so that the one-lock-per-batch rule of LockAsset still holds
+// while tasks run concurrently. All tasks are executed regardless of failures, and any errors are joined
+// into the returned error.
+//
+// If workers is less than one, a single worker is used. If the context is cancelled, no further tasks
+// are started and ctx.Err() is included in the returned error.
+func (lo *LoadingOperation) ExecuteParallel(ctx *flinch.Context, workers int) error {
+	workers = max(1, min(workers, len(lo.tasks)))
+
+	tasks := make(chan LoadingTask)
+	errs := make([]error, workers)
+	failed := atomic.Int64{}
+	canceled := atomic.Bool{}
+
+	// The first worker reuses the operation batch ID, the others are assigned fresh ones.
+	batchIDs := make([]uint64, workers)
+	batchIDs[0] = lo.batchID
+	for i := 1; i < workers; i++ {
+		batchIDs[i] = batchID.Add(1)
+	}
+
+	collector := lo.rs.collectBatch(batchIDs...)
+
+	wg := sync.WaitGroup{}
+	for i, workerBatchID := range batchIDs {
+		wg.Go(func() {
+			for task := range tasks {
+				if ctx.Err() != nil {
+					canceled.Store(true)
+					continue
+				}
+				if err := task(ctx, lo.rs, workerBatchID); err != nil {
+					errs[i] = errors.Join(errs[i], err)
+					failed.Add(1)
+				}
+			}
+		})
+	}
+
+feed:
+	for _, task := range lo.tasks {
+		select {
+		case tasks <- task:
+		case <-ctx.Done():
+			canceled.Store(true)
+			break feed
+		}
+	}
+	close(tasks)
+
+	wg.Wait()
+
+	collector.finish(len(lo.tasks), int(failed.Load()))
+
+	if canceled.Load() {
+		errs = append(errs, ctx.Err())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Start begins executing the LoadingOperation in the background and returns immediately.
+//
+// Tasks are executed sequentially, as with Execute. The returned LoadingHandle can be polled
+// from the game loop to report progress while the operation runs, and cancelled to abort it.
+func (lo *LoadingOperation) Start(ctx *flinch.Context) *LoadingHandle {
+	return lo.start(ctx, func(ctx *flinch.Context, op *LoadingOperation) error {
+		return op.Execute(ctx)
+	})
+}
+
+// StartParallel begins executing the LoadingOperation in the background using a bounded pool of workers,
+// as with ExecuteParallel, and returns immediately.
+func (lo *LoadingOperation) StartParallel(ctx *flinch.Context, workers int) *LoadingHandle {
+	return lo.start(ctx, func(ctx *flinch.Context, op *LoadingOperation) error {
+		return op.ExecuteParallel(ctx, workers)
+	})
+}
+
+// start wraps each task of the LoadingOperation with progress tracking and runs the given
+// execution function on a new goroutine.
+func (lo *LoadingOperation) start(ctx *flinch.Context, execute func(ctx *flinch.Context, op *LoadingOperation) error) *LoadingHandle {
+	cancelCtx, cancel := context.WithCancel(ctx)
+
+	handle := &LoadingHandle{
+		total:  len(lo.tasks),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	op := &LoadingOperation{
+		batchID: lo.batchID,
+		rs:      lo.rs,
+		tasks:   make([]LoadingTask, len(lo.tasks)),
+	}
+	for i, task := range lo.tasks {
+		op.tasks[i] = handle.track(task)
+	}
+
+	go func() {
+		defer cancel()
+
+		handle.err = execute(ctx.WithContext(cancelCtx), op)
+		close(handle.done)
+	}()
+
+	return handle
+}
+
+// AssetEvent describes the completion of work on a single asset within a loading operation.
+//
+// An event is emitted each time a batch releases the AssetLock it acquired for an asset.
+type AssetEvent struct {
+	Asset   Asset  // The asset that was completed
+	BatchID uint64 // The batch that completed the asset
+}
+
+// LoadingHandle tracks a LoadingOperation running in the background.
+//
+// LoadingHandle is safe for concurrent use by multiple goroutines.
+type LoadingHandle struct {
+	total     int
+	completed atomic.Int64
+
+	done   chan struct{}
+	err    error
+	cancel context.CancelFunc
+
+	events []AssetEvent
+	mu     sync.Mutex
+}
+
+// Progress returns the number of completed tasks and the total number of tasks in the operation.
+func (lh *LoadingHandle) Progress() (done, total int) {
+	return int(lh.completed.Load()), lh.total
+}
+
+// Done returns a channel that is closed once every task in the operation has finished.
+func (lh *LoadingHandle) Done() <-chan struct{} {
+	return lh.done
+}
+
+// Err returns the error produced by the operation.
+//
+// Err returns nil until the operation has finished.
+func (lh *LoadingHandle) Err() error {
+	select {
+	case <-lh.done:
+		return lh.err
+	default:
+		return nil
+	}
+}
+
+// Cancel aborts the operation. Tasks that have not started are skipped, and the operation
+// finishes with a context.Canceled error unless it had already completed.
+func (lh *LoadingHandle) Cancel() {
+	lh.cancel()
+}
+
+// Events returns the asset completion events emitted since the last call to Events.
+//
+// Events are returned in the order they occurred, and each event is returned exactly once.
+func (lh *LoadingHandle) Events() []AssetEvent {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	events := lh.events
+	lh.events = nil
+	return events
+}
+
+// track wraps a task so that its asset lock releases are recorded as events and its completion
+// advances the progress of the handle.
+func (lh *LoadingHandle) track(task LoadingTask) LoadingTask {
+	return func(ctx *flinch.Context, rs *ResourceSystem, batchID uint64) error {
+		rs.observe(batchID, lh.assetCompleted)
+		defer rs.unobserve(batchID)
+		defer lh.completed.Add(1)
+
+		return task(ctx, rs, batchID)
+	}
+}
+
+func (lh *LoadingHandle) assetCompleted(event AssetEvent) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	lh.events = append(lh.events, event)
+}
+
+// TaskFailure records a loading task that failed.
+type TaskFailure struct {
+	Task int   // Index of the task within the LoadingOperation
+	Err  error // The error returned by the task
+}
+
+// LoadReport summarizes the outcome of a LoadingOperation executed with ExecuteAll.
+type LoadReport struct {
+	Tasks    int           // Number of tasks in the operation
+	Failures []TaskFailure // Tasks that failed, in task order
+}
+
+// Err returns the errors of every failed task joined together, or nil if all tasks succeeded.
+func (r *LoadReport) Err() error {
+	errs := make([]error, len(r.Failures))
+	for i, failure := range r.Failures {
+		errs[i] = failure.Err
+	}
+	return errors.Join(errs...)
+}
+
+// AssetErrors returns every *AssetError found in the errors of the failed tasks.
+func (r *LoadReport) AssetErrors() []*AssetError {
+	assetErrors := make([]*AssetError, 0)
+	for _, failure := range r.Failures {
+		collectAssetErrors(failure.Err, &assetErrors)
+	}
+	return assetErrors
+}
+
+// collectAssetErrors walks an error tree and appends every *AssetError it contains.
+func collectAssetErrors(err error, assetErrors *[]*AssetError) {
+	switch e := err.(type) {
+	case nil:
+		return
+	case *AssetError:
+		*assetErrors = append(*assetErrors, e)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			collectAssetErrors(inner, assetErrors)
+		}
+	case interface{ Unwrap() error }:
+		collectAssetErrors(e.Unwrap(), assetErrors)
+	}
+}
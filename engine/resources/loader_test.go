@@ -0,0 +1,280 @@
+package resources_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func newContext() *flinch.Context {
+	return flinch.NewContext(context.Background(), io.Discard)
+}
+
+// newFiles returns n in-memory asset files, along with their assets in path order.
+func newFiles(n int) (map[string][]byte, []resources.Asset) {
+	paths := make([]string, n)
+	for i := range n {
+		paths[i] = fmt.Sprintf("assets/file%02d.bin", i)
+	}
+	return resourcestest.Files(paths...)
+}
+
+func TestExecuteParallelBatchesHoldOneLock(t *testing.T) {
+	files, assets := newFiles(8)
+	rs := resourcestest.NewSystem("parallel", files)
+
+	const workers = 4
+
+	var (
+		mu      sync.Mutex
+		active  = make(map[uint64]int)
+		batches = make(map[uint64]struct{})
+		ran     atomic.Int64
+	)
+
+	op := rs.CreateBatch()
+	for i := range 64 {
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			mu.Lock()
+			active[batchID]++
+			batches[batchID] = struct{}{}
+			concurrent := active[batchID]
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				active[batchID]--
+				mu.Unlock()
+			}()
+
+			if concurrent > 1 {
+				return fmt.Errorf("batch %d runs %d tasks at once", batchID, concurrent)
+			}
+
+			asset := assets[i%len(assets)]
+			lock, err := rs.LockAssetContext(ctx, batchID, asset)
+			if err != nil {
+				return err
+			}
+			defer lock.Release()
+
+			if _, err := rs.ReadBytesContext(ctx, asset); err != nil {
+				return err
+			}
+
+			ran.Add(1)
+			return nil
+		})
+	}
+
+	if err := op.ExecuteParallel(newContext(), workers); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() != 64 {
+		t.Errorf("ran %d tasks, want 64", ran.Load())
+	}
+	if len(batches) > workers {
+		t.Errorf("tasks ran under %d batches, want at most %d", len(batches), workers)
+	}
+}
+
+func TestExecuteParallelJoinsErrors(t *testing.T) {
+	rs := resourcestest.NewSystem("errors", map[string][]byte{})
+
+	failures := make([]error, 5)
+	op := rs.CreateBatch()
+	for i := range failures {
+		failures[i] = fmt.Errorf("task %d failed", i)
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			return failures[i]
+		})
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			return nil
+		})
+	}
+
+	err := op.ExecuteParallel(newContext(), 3)
+	for _, failure := range failures {
+		if !errors.Is(err, failure) {
+			t.Errorf("error %v does not include %q", err, failure)
+		}
+	}
+}
+
+func TestExecuteParallelCancel(t *testing.T) {
+	rs := resourcestest.NewSystem("cancel", map[string][]byte{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran atomic.Int64
+	op := rs.CreateBatch()
+	for range 32 {
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			ran.Add(1)
+			cancel()
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+
+	err := op.ExecuteParallel(newContext().WithContext(ctx), 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if n := ran.Load(); n == 0 || n > 2 {
+		t.Errorf("ran %d tasks after cancellation, want 1 or 2", n)
+	}
+}
+
+// lockTask returns a task that locks and reads the asset.
+func lockTask(asset resources.Asset) resources.LoadingTask {
+	return func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+		lock, err := rs.LockAssetContext(ctx, batchID, asset)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		time.Sleep(time.Millisecond)
+		_, err = rs.ReadBytesContext(ctx, asset)
+		return err
+	}
+}
+
+func TestStartReportsProgressAndEvents(t *testing.T) {
+	files, assets := newFiles(8)
+	rs := resourcestest.NewSystem("start", files)
+
+	op := rs.CreateBatch()
+	for _, asset := range assets {
+		op.AddTask(lockTask(asset))
+	}
+
+	handle := op.Start(newContext())
+
+	events := make([]resources.AssetEvent, 0)
+	previous := 0
+	for finished := false; !finished; {
+		select {
+		case <-handle.Done():
+			finished = true
+		default:
+			time.Sleep(100 * time.Microsecond)
+		}
+
+		done, total := handle.Progress()
+		if done < previous || done > total || total != len(assets) {
+			t.Fatalf("got progress (%d, %d) after %d tasks, want monotonic progress up to %d", done, total, previous, len(assets))
+		}
+		previous = done
+		events = append(events, handle.Events()...)
+	}
+	events = append(events, handle.Events()...)
+
+	if err := handle.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if done, total := handle.Progress(); done != total || total != len(assets) {
+		t.Fatalf("got progress (%d, %d), want (%d, %d)", done, total, len(assets), len(assets))
+	}
+
+	completed := make([]resources.Asset, len(events))
+	for i, event := range events {
+		completed[i] = event.Asset
+	}
+	if !slices.Equal(completed, assets) {
+		t.Fatalf("got events for %v, want one event per asset %v", completed, assets)
+	}
+}
+
+func TestStartParallelJoinsErrors(t *testing.T) {
+	files, assets := newFiles(6)
+	rs := resourcestest.NewSystem("start", files)
+
+	release := make(chan struct{})
+	failures := []error{errors.New("first task failed"), errors.New("second task failed")}
+
+	op := rs.CreateBatch()
+	for i, asset := range assets {
+		op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+			<-release
+			if err := lockTask(asset)(ctx, rs, batchID); err != nil {
+				return err
+			}
+			if i < len(failures) {
+				return failures[i]
+			}
+			return nil
+		})
+	}
+
+	handle := op.StartParallel(newContext(), 3)
+	if err := handle.Err(); err != nil {
+		t.Fatalf("got error %v before the operation finished, want nil", err)
+	}
+	close(release)
+	<-handle.Done()
+
+	err := handle.Err()
+	for _, failure := range failures {
+		if !errors.Is(err, failure) {
+			t.Errorf("error %v does not include %q", err, failure)
+		}
+	}
+	if done, total := handle.Progress(); done != total || total != len(assets) {
+		t.Fatalf("got progress (%d, %d), want (%d, %d)", done, total, len(assets), len(assets))
+	}
+
+	completed := make([]resources.Asset, 0)
+	for _, event := range handle.Events() {
+		completed = append(completed, event.Asset)
+	}
+	slices.Sort(completed)
+	if want := slices.Sorted(slices.Values(assets)); !slices.Equal(completed, want) {
+		t.Fatalf("got events for %v, want one event per asset %v", completed, want)
+	}
+	if events := handle.Events(); len(events) != 0 {
+		t.Fatalf("got events %v again, want each event once", events)
+	}
+}
+
+func TestStartCancel(t *testing.T) {
+	files, assets := newFiles(4)
+	rs := resourcestest.NewSystem("start", files)
+
+	started := make(chan struct{})
+	op := rs.CreateBatch()
+	op.AddTask(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	for _, asset := range assets {
+		op.AddTask(lockTask(asset))
+	}
+
+	handle := op.Start(newContext())
+	<-started
+	handle.Cancel()
+	<-handle.Done()
+
+	if err := handle.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if done, total := handle.Progress(); done != 1 || total != len(assets)+1 {
+		t.Fatalf("got progress (%d, %d), want (1, %d)", done, total, len(assets)+1)
+	}
+	if events := handle.Events(); len(events) != 0 {
+		t.Fatalf("got events %v for tasks that never ran", events)
+	}
+}
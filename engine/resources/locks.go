@@ -0,0 +1,359 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================== Multi-Asset Locking ==============================
+
+// ErrLockBusy is returned when an asset lock could not be acquired because another batch holds it.
+var ErrLockBusy = errors.New("asset is locked by another batch")
+
+// AssetLockSet guards several assets of a ResourceSystem acquired together with LockAssets.
+//
+// The AssetLockSet must be released by calling Release() exactly once when done. Calling Release()
+// multiple times will panic.
+type AssetLockSet struct {
+	noCopy
+
+	locks    []*AssetLock // Locks of the assets, in the order they were acquired
+	assets   []Asset
+	released bool
+}
+
+// Assets returns the locked assets, in the canonical order they were acquired in.
+func (s *AssetLockSet) Assets() []Asset {
+	return slices.Clone(s.assets)
+}
+
+// Release unlocks every asset of the set, in the reverse order they were acquired in.
+//
+// This method must be called exactly once. Calling Release() multiple times will panic.
+func (s *AssetLockSet) Release() {
+	if s.released {
+		panic("AssetLockSet released multiple times. DO NOT release an AssetLockSet more than once.")
+	}
+	s.released = true
+
+	for _, lock := range slices.Backward(s.locks) {
+		lock.Release()
+	}
+	s.locks = nil
+}
+
+// LockAssets acquires the locks of several assets for a batch, blocking until all of them are available.
+//
+// Assets are always acquired in a canonical order, by increasing identifier, so that batches locking
+// overlapping sets of assets in the same ResourceSystem cannot deadlock each other. Duplicate assets are
+// locked once. Like LockAsset, a batch can only hold the locks of a single call at a time.
+//
+// The returned AssetLockSet must be released by calling Release() exactly once when done.
+func (rs *ResourceSystem) LockAssets(batchID uint64, assets ...Asset) *AssetLockSet {
+	set, err := rs.LockAssetsContext(context.Background(), batchID, assets...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return set
+}
+
+// LockAssetsContext behaves like LockAssets, but returns an error instead of panicking, and stops waiting
+// for the assets when the context is cancelled.
+//
+// Errors are reported as with LockAssetContext. If the context is cancelled before every lock is acquired,
+// the locks acquired so far are released and ctx.Err() is returned.
+func (rs *ResourceSystem) LockAssetsContext(ctx context.Context, batchID uint64, assets ...Asset) (*AssetLockSet, error) {
+	locks, err := rs.lockAssets(batchID, assets, func(m assetMutex) error {
+		return m.Lock(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newAssetLockSet(locks), nil
+}
+
+// TryLockAssets acquires the locks of several assets for a batch without blocking.
+//
+// If any of the assets is locked by another batch, no lock is held and an *AssetError wrapping ErrLockBusy
+// naming that asset is returned. Other errors are reported as with LockAssetContext.
+func (rs *ResourceSystem) TryLockAssets(batchID uint64, assets ...Asset) (*AssetLockSet, error) {
+	locks, err := rs.lockAssets(batchID, assets, func(m assetMutex) error {
+		if !m.TryLock() {
+			return ErrLockBusy
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newAssetLockSet(locks), nil
+}
+
+// LockAssetsTimeout behaves like LockAssets, but gives up once the timeout has elapsed.
+//
+// If the locks could not all be acquired in time, no lock is held and an *AssetError wrapping ErrLockBusy
+// naming the asset still being waited for is returned. Other errors are reported as with LockAssetContext.
+func (rs *ResourceSystem) LockAssetsTimeout(batchID uint64, timeout time.Duration, assets ...Asset) (*AssetLockSet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	locks, err := rs.lockAssets(batchID, assets, func(m assetMutex) error {
+		if err := m.Lock(ctx); err != nil {
+			return fmt.Errorf("%w: timed out after %s", ErrLockBusy, timeout)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newAssetLockSet(locks), nil
+}
+
+func newAssetLockSet(locks []*AssetLock) *AssetLockSet {
+	set := &AssetLockSet{
+		locks:  locks,
+		assets: make([]Asset, len(locks)),
+	}
+	for i, lock := range locks {
+		set.assets[i] = lock.asset
+	}
+	return set
+}
+
+// lockAssets acquires the locks of the assets for a batch in canonical order, using acquire to lock each
+// asset mutex. If an asset cannot be acquired, the locks acquired so far are released.
+//
+// Errors returned by acquire are returned as-is if they are context errors, and wrapped in an *AssetError
+// naming the asset otherwise.
+func (rs *ResourceSystem) lockAssets(batchID uint64, assets []Asset, acquire func(m assetMutex) error) ([]*AssetLock, error) {
+	assets = slices.Compact(slices.Sorted(slices.Values(assets)))
+	if len(assets) == 0 {
+		return nil, nil
+	}
+
+	rs.mu.Lock()
+	paths := make([]string, len(assets))
+	for i, asset := range assets {
+		path, exists := rs.manifest[asset]
+		if !exists {
+			rs.mu.Unlock()
+			return nil, rs.assetError("lock", asset, ErrUnknownAsset)
+		}
+		paths[i] = path
+	}
+
+	if _, exists := rs.locks[batchID]; exists {
+		rs.mu.Unlock()
+		return nil, rs.assetError("lock", assets[0], fmt.Errorf("%w: batch %d", ErrBatchHoldsLock, batchID))
+	}
+
+	locks := make([]*AssetLock, len(assets))
+	for i, asset := range assets {
+		assetMutex, exists := rs.assetMu[asset]
+		if !exists {
+			assetMutex = newAssetMutex()
+			rs.assetMu[asset] = assetMutex
+		}
+
+		lock := assetLocks.Get().(*AssetLock)
+		lock.batchID = batchID
+		lock.asset = asset
+		lock.assetMu = assetMutex
+		lock.rs = rs
+		locks[i] = lock
+	}
+
+	// The first lock marks the batch as holding locks. Locks are released in reverse order, so the
+	// batch holds locks until the last of them is released.
+	rs.locks[batchID] = locks[0]
+	rs.mu.Unlock()
+
+	if lockDebug.enabled.Load() {
+		lockDebug.acquiring(rs, batchID, assets, paths)
+	}
+
+	for i, lock := range locks {
+		start := time.Now()
+		if err := acquire(lock.assetMu); err != nil {
+			for _, abandoned := range locks[i:] {
+				rs.lockAbandoned(abandoned)
+			}
+			for _, acquired := range slices.Backward(locks[:i]) {
+				acquired.Release()
+			}
+
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			return nil, rs.assetError("lock", assets[i], err)
+		}
+		rs.meterAcquired(lock, paths[i], time.Since(start))
+
+		if lockDebug.enabled.Load() {
+			lockDebug.acquired(rs, batchID, assets[i], paths[i])
+		}
+	}
+
+	return locks, nil
+}
+
+// ============================== Lock Debugging ==============================
+
+// LockedAsset identifies an asset involved in a lock-order inversion.
+type LockedAsset struct {
+	System string // Name of the resource system
+	Asset  Asset
+	Path   string
+}
+
+func (a LockedAsset) String() string {
+	return fmt.Sprintf("asset 0x%x (%s) in resource system %s", a.Asset, a.Path, a.System)
+}
+
+// LockInversion describes two batches that acquired the locks of the same two assets in opposite orders,
+// which deadlocks if both batches hold their first lock at the same time.
+type LockInversion struct {
+	Batch      uint64      // The batch that acquired Second while holding First
+	First      LockedAsset // The asset Batch held
+	Second     LockedAsset // The asset Batch acquired
+	OtherBatch uint64      // A batch that previously acquired First while holding Second
+}
+
+func (i LockInversion) String() string {
+	return fmt.Sprintf("lock-order inversion: batch %d locked %s while holding %s, but batch %d locked them in the opposite order",
+		i.Batch, i.Second, i.First, i.OtherBatch)
+}
+
+// SetLockDebug enables or disables the detection of lock-order inversions across every ResourceSystem.
+//
+// Within a ResourceSystem, a batch only ever holds the locks of a single LockAsset or LockAssets call,
+// which are acquired in canonical order. Batches may however hold locks in several resource systems at
+// once. In debug mode, the order in which each batch acquires locks while already holding others is
+// recorded, and acquiring two locks in the opposite order of another batch is reported as a LockInversion,
+// before blocking on the lock. Debug mode adds overhead to every lock, and is intended for development.
+func SetLockDebug(enabled bool) {
+	lockDebug.mu.Lock()
+	defer lockDebug.mu.Unlock()
+
+	lockDebug.enabled.Store(enabled)
+	if !enabled {
+		clear(lockDebug.held)
+		clear(lockDebug.order)
+	}
+}
+
+// LockInversions returns the lock-order inversions detected while debug mode was enabled, each pair of
+// assets being reported once.
+func LockInversions() []LockInversion {
+	lockDebug.mu.Lock()
+	defer lockDebug.mu.Unlock()
+	return slices.Clone(lockDebug.inversions)
+}
+
+// OnLockInversion registers a function that is called each time a lock-order inversion is detected.
+//
+// The function is called on the goroutine acquiring the lock, before it blocks, and must not acquire asset
+// locks itself.
+func OnLockInversion(fn func(LockInversion)) {
+	lockDebug.mu.Lock()
+	defer lockDebug.mu.Unlock()
+	lockDebug.subscribers = append(lockDebug.subscribers, fn)
+}
+
+// lockDebug tracks the order in which batches acquire asset locks while debug mode is enabled.
+var lockDebug = &lockTracker{
+	held:     make(map[uint64][]lockedKey),
+	order:    make(map[[2]lockKey]uint64),
+	reported: make(map[[2]lockKey]struct{}),
+}
+
+type lockKey struct {
+	rs    *ResourceSystem
+	asset Asset
+}
+
+type lockedKey struct {
+	lockKey
+	path string
+}
+
+type lockTracker struct {
+	enabled atomic.Bool
+
+	held        map[uint64][]lockedKey  // Locks currently held by each batch
+	order       map[[2]lockKey]uint64   // Batch that first acquired the second lock while holding the first
+	reported    map[[2]lockKey]struct{} // Inversions already reported, keyed by both orders of their assets
+	inversions  []LockInversion
+	subscribers []func(LockInversion)
+
+	mu sync.Mutex
+}
+
+// acquiring records that a batch is about to acquire the locks of assets, reporting inversions with the
+// locks it already holds in other resource systems.
+func (t *lockTracker) acquiring(rs *ResourceSystem, batchID uint64, assets []Asset, paths []string) {
+	t.mu.Lock()
+	detected := make([]LockInversion, 0)
+	for _, held := range t.held[batchID] {
+		for i, asset := range assets {
+			next := lockKey{rs: rs, asset: asset}
+			if _, exists := t.order[[2]lockKey{held.lockKey, next}]; !exists {
+				t.order[[2]lockKey{held.lockKey, next}] = batchID
+			}
+
+			otherBatch, inverted := t.order[[2]lockKey{next, held.lockKey}]
+			if !inverted || otherBatch == batchID {
+				continue
+			}
+
+			if _, reported := t.reported[[2]lockKey{held.lockKey, next}]; reported {
+				continue
+			}
+			t.reported[[2]lockKey{held.lockKey, next}] = struct{}{}
+			t.reported[[2]lockKey{next, held.lockKey}] = struct{}{}
+
+			detected = append(detected, LockInversion{
+				Batch:      batchID,
+				First:      LockedAsset{System: held.rs.name, Asset: held.asset, Path: held.path},
+				Second:     LockedAsset{System: rs.name, Asset: asset, Path: paths[i]},
+				OtherBatch: otherBatch,
+			})
+		}
+	}
+	t.inversions = append(t.inversions, detected...)
+	subscribers := t.subscribers
+	t.mu.Unlock()
+
+	for _, inversion := range detected {
+		for _, fn := range subscribers {
+			fn(inversion)
+		}
+	}
+}
+
+// acquired records that a batch holds the lock of an asset.
+func (t *lockTracker) acquired(rs *ResourceSystem, batchID uint64, asset Asset, path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.held[batchID] = append(t.held[batchID], lockedKey{lockKey: lockKey{rs: rs, asset: asset}, path: path})
+}
+
+// released records that a batch no longer holds the lock of an asset.
+func (t *lockTracker) released(rs *ResourceSystem, batchID uint64, asset Asset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	held := slices.DeleteFunc(t.held[batchID], func(k lockedKey) bool {
+		return k.rs == rs && k.asset == asset
+	})
+	if len(held) == 0 {
+		delete(t.held, batchID)
+	} else {
+		t.held[batchID] = held
+	}
+}
@@ -0,0 +1,150 @@
+package resources_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestLockAssetsOppositeOrdersDoNotDeadlock(t *testing.T) {
+	files, assets := newFiles(4)
+	rs := resourcestest.NewSystem("locks", files)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		wg := sync.WaitGroup{}
+		for i := range 8 {
+			order := slices.Clone(assets)
+			if i%2 == 1 {
+				slices.Reverse(order)
+			}
+			wg.Go(func() {
+				for range 200 {
+					set := rs.LockAssets(resources.NewBatchID(), order...)
+					set.Release()
+				}
+			})
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batches locking overlapping assets in opposite orders deadlocked")
+	}
+}
+
+func TestLockAssetsCanonicalOrder(t *testing.T) {
+	files, assets := newFiles(3)
+	rs := resourcestest.NewSystem("locks", files)
+
+	set := rs.LockAssets(resources.NewBatchID(), assets[2], assets[0], assets[1], assets[0])
+	defer set.Release()
+
+	want := slices.Sorted(slices.Values(assets))
+	if got := set.Assets(); !slices.Equal(got, want) {
+		t.Fatalf("got assets %v, want %v", got, want)
+	}
+}
+
+func TestTryLockAssetsBusy(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("locks", files)
+
+	held := rs.LockAsset(resources.NewBatchID(), assets[1])
+
+	_, err := rs.TryLockAssets(resources.NewBatchID(), assets...)
+	if !errors.Is(err, resources.ErrLockBusy) {
+		t.Fatalf("got error %v, want ErrLockBusy", err)
+	}
+	var assetErr *resources.AssetError
+	if !errors.As(err, &assetErr) || assetErr.Asset != assets[1] {
+		t.Fatalf("got error %v, want an AssetError naming the busy asset", err)
+	}
+
+	// The failed attempt released the assets it had acquired.
+	free, err := rs.TryLockAssets(resources.NewBatchID(), assets[0])
+	if err != nil {
+		t.Fatalf("got error %v, want the other asset to be free", err)
+	}
+	free.Release()
+
+	if _, err := rs.LockAssetsTimeout(resources.NewBatchID(), 10*time.Millisecond, assets...); !errors.Is(err, resources.ErrLockBusy) {
+		t.Fatalf("got error %v, want ErrLockBusy after the timeout", err)
+	}
+
+	held.Release()
+
+	set, err := rs.TryLockAssets(resources.NewBatchID(), assets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Release()
+}
+
+func TestLockInversionDetection(t *testing.T) {
+	resources.SetLockDebug(true)
+	defer resources.SetLockDebug(false)
+
+	filesA, assetsA := newFiles(1)
+	filesB, assetsB := newFiles(1)
+	rsA := resourcestest.NewSystem("a", filesA)
+	rsB := resourcestest.NewSystem("b", filesB)
+
+	var (
+		mu       sync.Mutex
+		detected []resources.LockInversion
+	)
+	resources.OnLockInversion(func(inversion resources.LockInversion) {
+		mu.Lock()
+		defer mu.Unlock()
+		detected = append(detected, inversion)
+	})
+	reported := len(resources.LockInversions())
+
+	lockBoth := func(first, second *resources.ResourceSystem, firstAsset, secondAsset resources.Asset) uint64 {
+		batchID := resources.NewBatchID()
+		outer := first.LockAsset(batchID, firstAsset)
+		inner := second.LockAsset(batchID, secondAsset)
+		inner.Release()
+		outer.Release()
+		return batchID
+	}
+
+	// Locking in the same order again is not an inversion.
+	forward := lockBoth(rsA, rsB, assetsA[0], assetsB[0])
+	lockBoth(rsA, rsB, assetsA[0], assetsB[0])
+	if len(resources.LockInversions()) != reported {
+		t.Fatal("consistent lock order reported as an inversion")
+	}
+
+	backward := lockBoth(rsB, rsA, assetsB[0], assetsA[0])
+	lockBoth(rsB, rsA, assetsB[0], assetsA[0])
+
+	inversions := resources.LockInversions()[reported:]
+	if len(inversions) != 1 {
+		t.Fatalf("got %d inversions, want the pair of assets reported once", len(inversions))
+	}
+
+	inversion := inversions[0]
+	if inversion.Batch != backward || inversion.OtherBatch != forward {
+		t.Fatalf("got batches %d and %d, want %d and %d", inversion.Batch, inversion.OtherBatch, backward, forward)
+	}
+	if inversion.First.System != "b" || inversion.Second.System != "a" || inversion.Second.Asset != assetsA[0] {
+		t.Fatalf("got inversion %v, want batch %d locking a while holding b", inversion, backward)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(detected) != 1 || detected[0] != inversion {
+		t.Fatalf("subscriber received %v, want %v", detected, inversion)
+	}
+}
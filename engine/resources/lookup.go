@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"cmp"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ============================== Lookup ==============================
+
+// Lookup returns the asset with the given path.
+//
+// The path may be the manifest path of the asset or, for resource systems with TrimRoot enabled, the
+// path with its root directory trimmed, as it appears within the filesystem.
+func (rs *ResourceSystem) Lookup(assetPath string) (Asset, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	asset, exists := rs.paths[path.Clean(assetPath)]
+	return asset, exists
+}
+
+// Path returns the manifest path of the asset.
+func (rs *ResourceSystem) Path(asset Asset) (string, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	assetPath, exists := rs.manifest[asset]
+	return assetPath, exists
+}
+
+// Contains reports whether the asset exists in the manifest of the resource system.
+func (rs *ResourceSystem) Contains(asset Asset) bool {
+	_, exists := rs.Path(asset)
+	return exists
+}
+
+// Assets returns an iterator over every asset in the resource system, ordered by manifest path.
+func (rs *ResourceSystem) Assets() iter.Seq[Asset] {
+	entries := rs.sortedEntries()
+
+	return func(yield func(Asset) bool) {
+		for _, entry := range entries {
+			if !yield(entry.asset) {
+				return
+			}
+		}
+	}
+}
+
+// Glob returns an iterator over the assets whose paths match the pattern, ordered by manifest path.
+//
+// Patterns use the syntax of path.Match, extended with "**" path segments that match any number of
+// directories, e.g. "assets/tiled/**/*.tmx". As with Lookup, patterns may be written against the manifest
+// path or, for resource systems with TrimRoot enabled, the trimmed path.
+//
+// The only possible error is path.ErrBadPattern, when the pattern is malformed.
+func (rs *ResourceSystem) Glob(pattern string) (iter.Seq[Asset], error) {
+	segments := strings.Split(path.Clean(pattern), "/")
+
+	// Validate the pattern up front so that iteration cannot fail.
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := rs.sortedEntries()
+
+	return func(yield func(Asset) bool) {
+		for _, entry := range entries {
+			matched := matchSegments(segments, strings.Split(entry.path, "/"))
+			if !matched && rs.options.TrimRoot {
+				matched = matchSegments(segments, strings.Split(trimAssetPathRoot(entry.path), "/"))
+			}
+			if matched && !yield(entry.asset) {
+				return
+			}
+		}
+	}, nil
+}
+
+type manifestEntry struct {
+	asset Asset
+	path  string
+}
+
+// sortedEntries returns a snapshot of the manifest ordered by path.
+func (rs *ResourceSystem) sortedEntries() []manifestEntry {
+	rs.mu.RLock()
+	entries := make([]manifestEntry, 0, len(rs.manifest))
+	for asset, assetPath := range rs.manifest {
+		entries = append(entries, manifestEntry{asset: asset, path: assetPath})
+	}
+	rs.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b manifestEntry) int {
+		return cmp.Compare(a.path, b.path)
+	})
+
+	return entries
+}
+
+// matchSegments matches path segments against pattern segments, where a "**" pattern segment matches
+// zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
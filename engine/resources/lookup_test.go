@@ -0,0 +1,174 @@
+package resources_test
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+var lookupPaths = []string{
+	"assets/f.json",
+	"assets/images/a.png",
+	"assets/images/ui/b.png",
+	"assets/tiled/maps/c.tmx",
+	"assets/tiled/maps/deep/d.tmx",
+	"assets/tiled/sets/e.tsx",
+}
+
+// newLookupSystem creates a resource system with the lookup paths, and returns it along with the assets of
+// the paths.
+func newLookupSystem(trimRoot bool) (*resources.ResourceSystem, map[string]resources.Asset) {
+	files, assets := resourcestest.Files(lookupPaths...)
+	rs := resources.NewResourceSystem("lookup", resourcestest.NewManifest(files).Assets, resources.ResourceSystemOptions{
+		TrimRoot: trimRoot,
+	})
+
+	byPath := make(map[string]resources.Asset, len(assets))
+	for i, asset := range assets {
+		byPath[lookupPaths[i]] = asset
+	}
+	return rs, byPath
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string // Manifest path of the asset found, or empty if none is found
+		trimmed string // Manifest path of the asset found with TrimRoot enabled
+	}{
+		{path: "assets/images/a.png", want: "assets/images/a.png", trimmed: "assets/images/a.png"},
+		{path: "images/a.png", trimmed: "assets/images/a.png"},
+		{path: "./assets/images/ui/../a.png", want: "assets/images/a.png", trimmed: "assets/images/a.png"},
+		{path: "tiled/maps/deep/d.tmx", trimmed: "assets/tiled/maps/deep/d.tmx"},
+		{path: "assets/images/missing.png"},
+		{path: "a.png"},
+	}
+
+	for _, trimRoot := range []bool{false, true} {
+		rs, assets := newLookupSystem(trimRoot)
+
+		for _, test := range tests {
+			want := test.want
+			if trimRoot {
+				want = test.trimmed
+			}
+
+			asset, exists := rs.Lookup(test.path)
+			if exists != (want != "") || asset != assets[want] {
+				t.Errorf("TrimRoot %v: Lookup(%q) = (0x%x, %v), want %q", trimRoot, test.path, asset, exists, want)
+				continue
+			}
+			if !exists {
+				continue
+			}
+
+			// Paths are the manifest paths, whichever form the asset was looked up with.
+			if assetPath, exists := rs.Path(asset); !exists || assetPath != want {
+				t.Errorf("TrimRoot %v: Path(0x%x) = (%q, %v), want %q", trimRoot, asset, assetPath, exists, want)
+			}
+			if !rs.Contains(asset) {
+				t.Errorf("TrimRoot %v: Contains(0x%x) = false for %q", trimRoot, asset, want)
+			}
+		}
+
+		if _, exists := rs.Path(resources.Asset(0)); exists || rs.Contains(resources.Asset(0)) {
+			t.Errorf("TrimRoot %v: unknown asset reported as contained", trimRoot)
+		}
+	}
+}
+
+func TestAssetsOrderedByPath(t *testing.T) {
+	for _, trimRoot := range []bool{false, true} {
+		rs, assets := newLookupSystem(trimRoot)
+
+		want := make([]resources.Asset, len(lookupPaths))
+		for i, assetPath := range lookupPaths {
+			want[i] = assets[assetPath]
+		}
+		if got := slices.Collect(rs.Assets()); !slices.Equal(got, want) {
+			t.Errorf("TrimRoot %v: got assets %v, want %v", trimRoot, got, want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string // Manifest paths of the matching assets
+		trimmed []string // Manifest paths of the matching assets with TrimRoot enabled
+	}{
+		{
+			pattern: "assets/tiled/**/*.tmx",
+			want:    []string{"assets/tiled/maps/c.tmx", "assets/tiled/maps/deep/d.tmx"},
+			trimmed: []string{"assets/tiled/maps/c.tmx", "assets/tiled/maps/deep/d.tmx"},
+		},
+		{
+			pattern: "tiled/**/*.tmx",
+			trimmed: []string{"assets/tiled/maps/c.tmx", "assets/tiled/maps/deep/d.tmx"},
+		},
+		{
+			pattern: "**/*.png",
+			want:    []string{"assets/images/a.png", "assets/images/ui/b.png"},
+			trimmed: []string{"assets/images/a.png", "assets/images/ui/b.png"},
+		},
+		{
+			pattern: "*.json",
+			trimmed: []string{"assets/f.json"},
+		},
+		{
+			pattern: "assets/images/*",
+			want:    []string{"assets/images/a.png"},
+			trimmed: []string{"assets/images/a.png"},
+		},
+		{
+			pattern: "images/**",
+			trimmed: []string{"assets/images/a.png", "assets/images/ui/b.png"},
+		},
+		{
+			pattern: "assets/tiled/**",
+			want:    []string{"assets/tiled/maps/c.tmx", "assets/tiled/maps/deep/d.tmx", "assets/tiled/sets/e.tsx"},
+			trimmed: []string{"assets/tiled/maps/c.tmx", "assets/tiled/maps/deep/d.tmx", "assets/tiled/sets/e.tsx"},
+		},
+		{
+			pattern: "assets/**/maps/*/*.tmx",
+			want:    []string{"assets/tiled/maps/deep/d.tmx"},
+			trimmed: []string{"assets/tiled/maps/deep/d.tmx"},
+		},
+		{
+			pattern: "**",
+			want:    lookupPaths,
+			trimmed: lookupPaths,
+		},
+	}
+
+	for _, trimRoot := range []bool{false, true} {
+		rs, assets := newLookupSystem(trimRoot)
+
+		for _, test := range tests {
+			want := test.want
+			if trimRoot {
+				want = test.trimmed
+			}
+			wantAssets := make([]resources.Asset, len(want))
+			for i, assetPath := range want {
+				wantAssets[i] = assets[assetPath]
+			}
+
+			matches, err := rs.Glob(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Collect(matches); !slices.Equal(got, wantAssets) {
+				t.Errorf("TrimRoot %v: Glob(%q) = %v, want %v", trimRoot, test.pattern, got, want)
+			}
+		}
+
+		if _, err := rs.Glob("assets/[images/*.png"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("TrimRoot %v: got error %v, want path.ErrBadPattern", trimRoot, err)
+		}
+	}
+}
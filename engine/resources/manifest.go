@@ -0,0 +1,307 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// ============================== Serialized Manifests ==============================
+
+// Serialized manifests are emitted by `flinch-cli generate manifest --serialize` alongside the generated
+// Go source, so that content such as downloadable or user-supplied packs can register assets at runtime.
+//
+// The JSON form is an object with a version and a list of assets:
+//
+//	{"version": 1, "assets": [{"id": "0x6b45fe0a52c037d3", "path": "assets/images/SampleA.png", "size": 1024, "sha256": "..."}]}
+//
+// The binary form starts with the manifest magic. All integers are little-endian:
+//
+//	magic       [4]byte  "FLMF"
+//	version     uint16
+//	flags       uint16   reserved, must be zero
+//	count       uint32
+//	entries     count entries
+//	  asset     uint64
+//	  size      int64    size of the asset file, or -1 if the entry has no digest
+//	  sha256    [32]byte zero if the entry has no digest
+//	  pathLen   uint16
+//	  path      [pathLen]byte
+const (
+	// ManifestMagic identifies a binary manifest.
+	ManifestMagic = "FLMF"
+
+	// ManifestVersion is the serialized manifest version understood by this package.
+	ManifestVersion uint16 = 1
+
+	manifestHeaderSize = 4 + 2 + 2 + 4
+	manifestEntrySize  = 8 + 8 + 32 + 2
+)
+
+var (
+	// ErrManifestFormat is returned when a serialized manifest cannot be parsed.
+	ErrManifestFormat = errors.New("invalid asset manifest")
+
+	// ErrAssetConflict is returned when merging a manifest whose assets clash with those of a resource system.
+	ErrAssetConflict = errors.New("asset conflicts with an existing asset")
+)
+
+// Manifest is an AssetManifest loaded at runtime, together with the digests of its assets.
+type Manifest struct {
+	Assets  AssetManifest
+	Digests AssetDigests
+}
+
+type jsonManifest struct {
+	Version uint16      `json:"version"`
+	Assets  []jsonAsset `json:"assets"`
+}
+
+type jsonAsset struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// LoadManifest reads a serialized manifest from the filesystem.
+//
+// Both the JSON and the binary form are accepted; the form is detected from the contents of the file.
+func LoadManifest(fsys fs.FS, path string) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// ParseManifest parses a serialized manifest in either the JSON or the binary form.
+//
+// Parse errors wrap ErrManifestFormat.
+func ParseManifest(data []byte) (*Manifest, error) {
+	if bytes.HasPrefix(data, []byte(ManifestMagic)) {
+		return parseBinaryManifest(data)
+	}
+	return parseJSONManifest(data)
+}
+
+func parseJSONManifest(data []byte) (*Manifest, error) {
+	var document jsonManifest
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrManifestFormat, err)
+	}
+
+	if document.Version != ManifestVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrManifestFormat, document.Version)
+	}
+
+	manifest := newManifest(len(document.Assets))
+	for _, entry := range document.Assets {
+		id, err := strconv.ParseUint(entry.ID, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid asset id %q", ErrManifestFormat, entry.ID)
+		}
+
+		var digest *AssetDigest
+		if entry.SHA256 != "" {
+			digest = &AssetDigest{Size: entry.Size, SHA256: entry.SHA256}
+		}
+
+		if err := manifest.add(Asset(id), entry.Path, digest); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+func parseBinaryManifest(data []byte) (*Manifest, error) {
+	if len(data) < manifestHeaderSize {
+		return nil, fmt.Errorf("%w: truncated header", ErrManifestFormat)
+	}
+
+	version := binary.LittleEndian.Uint16(data[4:])
+	if version != ManifestVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrManifestFormat, version)
+	}
+
+	count := binary.LittleEndian.Uint32(data[8:])
+	data = data[manifestHeaderSize:]
+
+	manifest := newManifest(min(int(count), len(data)/manifestEntrySize))
+	for range count {
+		if len(data) < manifestEntrySize {
+			return nil, fmt.Errorf("%w: truncated entry", ErrManifestFormat)
+		}
+
+		asset := Asset(binary.LittleEndian.Uint64(data[0:]))
+		size := int64(binary.LittleEndian.Uint64(data[8:]))
+		sum := data[16:48]
+		pathLen := int(binary.LittleEndian.Uint16(data[48:]))
+		data = data[manifestEntrySize:]
+
+		if len(data) < pathLen {
+			return nil, fmt.Errorf("%w: truncated entry", ErrManifestFormat)
+		}
+		path := string(data[:pathLen])
+		data = data[pathLen:]
+
+		var digest *AssetDigest
+		if size >= 0 {
+			digest = &AssetDigest{Size: size, SHA256: hex.EncodeToString(sum)}
+		}
+
+		if err := manifest.add(asset, path, digest); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrManifestFormat)
+	}
+
+	return manifest, nil
+}
+
+func newManifest(size int) *Manifest {
+	return &Manifest{
+		Assets:  make(AssetManifest, size),
+		Digests: make(AssetDigests, size),
+	}
+}
+
+// add adds an entry to a manifest being parsed, rejecting invalid paths and duplicate identifiers.
+func (m *Manifest) add(asset Asset, path string, digest *AssetDigest) error {
+	if !fs.ValidPath(path) || path == "." {
+		return fmt.Errorf("%w: invalid path %q for asset 0x%x", ErrManifestFormat, path, asset)
+	}
+	if existing, exists := m.Assets[asset]; exists {
+		return fmt.Errorf("%w: asset 0x%x is declared for both %s and %s", ErrManifestFormat, asset, existing, path)
+	}
+
+	m.Assets[asset] = path
+	if digest != nil {
+		m.Digests[asset] = *digest
+	}
+	return nil
+}
+
+// ============================== Merging ==============================
+
+// Merge registers the assets of a manifest with the ResourceSystem.
+//
+// Merged assets behave exactly like those of the manifest the ResourceSystem was created with; their files
+// are typically provided by a layer pushed for the content that declared them. Paths are interpreted as
+// the paths of the ResourceSystem's own manifest, including the trimming of their root directory when
+// TrimRoot is enabled.
+//
+// Merging an asset that is already registered with the same path has no effect. If an asset identifier is
+// already registered with a different path, or a path is already registered for a different asset, nothing
+// is merged and an error joining an *AssetError wrapping ErrAssetConflict for each clash is returned.
+//
+// In strict mode, the manifest is also validated as described by SetStrict before anything is merged.
+func (rs *ResourceSystem) Merge(manifest *Manifest) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	// Validation reads the layers, so it runs without holding the lock. It is repeated if the manifest or
+	// the layers changed in the meantime, so that the merge is applied to the state that was validated.
+	for validated := !rs.strict.Load(); !validated; {
+		revision := rs.revision
+		rs.mu.Unlock()
+		err := rs.checkMerge(manifest)
+		rs.mu.Lock()
+
+		if err != nil {
+			return err
+		}
+		validated = rs.revision == revision
+	}
+
+	errs := make([]error, 0)
+	incoming := make(map[string]Asset, len(manifest.Assets))
+	for _, asset := range slices.Sorted(maps.Keys(manifest.Assets)) {
+		path := manifest.Assets[asset]
+		if existing, exists := rs.manifest[asset]; exists && existing != path {
+			errs = append(errs, &AssetError{
+				Op:     "merge",
+				System: rs.name,
+				Asset:  asset,
+				Path:   path,
+				Err:    fmt.Errorf("%w: identifier already registered for %s", ErrAssetConflict, existing),
+			})
+			continue
+		}
+
+		for _, key := range rs.pathKeys(path) {
+			existing, exists := rs.paths[key]
+			if !exists {
+				existing, exists = incoming[key]
+			}
+			incoming[key] = asset
+
+			if exists && existing != asset {
+				errs = append(errs, &AssetError{
+					Op:     "merge",
+					System: rs.name,
+					Asset:  asset,
+					Path:   path,
+					Err:    fmt.Errorf("%w: path already registered for asset 0x%x", ErrAssetConflict, existing),
+				})
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// The manifest and digests are copied rather than modified in place, as they are typically
+	// package-level variables shared with generated code.
+	merged := maps.Clone(rs.manifest)
+	if merged == nil {
+		merged = make(AssetManifest, len(manifest.Assets))
+	}
+	digests := maps.Clone(rs.options.Digests)
+	if digests == nil {
+		digests = make(AssetDigests, len(manifest.Digests))
+	}
+
+	for asset, path := range manifest.Assets {
+		merged[asset] = path
+		for _, key := range rs.pathKeys(path) {
+			rs.paths[key] = asset
+		}
+		if digest, exists := manifest.Digests[asset]; exists {
+			digests[asset] = digest
+		}
+	}
+
+	rs.manifest = merged
+	rs.options.Digests = digests
+	rs.revision++
+
+	return nil
+}
+
+// pathKeys returns the keys under which the path of an asset is indexed for Lookup.
+func (rs *ResourceSystem) pathKeys(path string) []string {
+	if rs.options.TrimRoot {
+		return []string{path, trimAssetPathRoot(path)}
+	}
+	return []string{path}
+}
@@ -0,0 +1,128 @@
+package resources_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+// encodeManifest encodes a manifest in the binary form. Assets without a digest are encoded without one.
+func encodeManifest(t *testing.T, manifest *resources.Manifest, order ...resources.Asset) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(resources.ManifestMagic)
+	binary.Write(buf, binary.LittleEndian, resources.ManifestVersion)
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, uint32(len(order)))
+
+	for _, asset := range order {
+		size, sum := int64(-1), make([]byte, 32)
+		if digest, exists := manifest.Digests[asset]; exists {
+			size = digest.Size
+			var err error
+			if sum, err = hex.DecodeString(digest.SHA256); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		path := manifest.Assets[asset]
+		binary.Write(buf, binary.LittleEndian, uint64(asset))
+		binary.Write(buf, binary.LittleEndian, size)
+		buf.Write(sum)
+		binary.Write(buf, binary.LittleEndian, uint16(len(path)))
+		buf.WriteString(path)
+	}
+
+	return buf.Bytes()
+}
+
+func newBinaryManifest(t *testing.T) (*resources.Manifest, []byte) {
+	t.Helper()
+
+	files, assets := resourcestest.Files("assets/a.bin", "assets/b.bin")
+	manifest := resourcestest.NewManifest(files)
+
+	// Logical assets provided only by variants have no digest.
+	manifest.Assets[0x10] = "assets/variants.png"
+
+	return manifest, encodeManifest(t, manifest, assets[0], assets[1], 0x10)
+}
+
+func TestParseBinaryManifest(t *testing.T) {
+	manifest, data := newBinaryManifest(t)
+
+	parsed, err := resources.ParseManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(parsed.Assets, manifest.Assets) {
+		t.Fatalf("got assets %v, want %v", parsed.Assets, manifest.Assets)
+	}
+	if !maps.Equal(parsed.Digests, manifest.Digests) {
+		t.Fatalf("got digests %v, want %v", parsed.Digests, manifest.Digests)
+	}
+}
+
+func TestParseManifestRejectsInvalidBinary(t *testing.T) {
+	_, data := newBinaryManifest(t)
+
+	for n := range len(data) {
+		if _, err := resources.ParseManifest(data[:n]); !errors.Is(err, resources.ErrManifestFormat) {
+			t.Fatalf("got error %v for a manifest truncated to %d of %d bytes, want ErrManifestFormat", err, n, len(data))
+		}
+	}
+
+	tests := map[string]func(data []byte){
+		"bad magic":     func(data []byte) { copy(data, "FLMX") },
+		"bad version":   func(data []byte) { binary.LittleEndian.PutUint16(data[4:], resources.ManifestVersion+1) },
+		"invalid path":  func(data []byte) { copy(data[len(data)-len("variants.png"):], "/variants.pn") },
+		"trailing data": func(data []byte) { binary.LittleEndian.PutUint32(data[8:], 2) },
+		"missing entry": func(data []byte) { binary.LittleEndian.PutUint32(data[8:], 4) },
+	}
+
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			corrupted := bytes.Clone(data)
+			corrupt(corrupted)
+
+			if _, err := resources.ParseManifest(corrupted); !errors.Is(err, resources.ErrManifestFormat) {
+				t.Fatalf("got error %v, want ErrManifestFormat", err)
+			}
+		})
+	}
+}
+
+func TestStrictMergeRevalidatesAfterLayerChange(t *testing.T) {
+	files, _ := newFiles(1)
+	rs := resourcestest.NewSystem("merge", files)
+	rs.SetStrict(true)
+
+	modFiles := map[string][]byte{"mods/extra.bin": []byte("extra")}
+	mods := resourcestest.NewFaultFS(resourcestest.NewFS(modFiles), 1)
+	mods.Inject(resourcestest.Delay("", 50*time.Millisecond))
+	rs.PushLayer("mods", mods)
+
+	// The mods layer is removed while the merge is validating the files it provides.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		rs.RemoveLayer("mods")
+	}()
+
+	err := rs.Merge(resourcestest.NewManifest(modFiles))
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v, want the missing file to be reported", err)
+	}
+	if rs.Contains(resourcestest.AssetOf("mods/extra.bin")) {
+		t.Fatal("asset merged although its layer was removed during validation")
+	}
+}
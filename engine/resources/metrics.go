@@ -0,0 +1,575 @@
+package resources
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/adm87/flinch/engine/flinch"
+)
+
+// ============================== Metrics ==============================
+
+// AssetMetrics describes the work done on an asset while a batch held its AssetLock.
+type AssetMetrics struct {
+	System   string        `json:"system"`
+	Asset    Asset         `json:"asset"`
+	Path     string        `json:"path"`
+	BatchID  uint64        `json:"batch_id"`
+	LockWait time.Duration `json:"lock_wait_ns"` // Time spent waiting to acquire the AssetLock
+	Read     time.Duration `json:"read_ns"`      // Time spent reading files opened for the asset
+	Decode   time.Duration `json:"decode_ns"`    // Time spent decoding the asset, excluding reads
+	Held     time.Duration `json:"held_ns"`      // Time the AssetLock was held
+	Bytes    int64         `json:"bytes"`        // Bytes read from files opened for the asset
+}
+
+// BatchMetrics summarizes a LoadingOperation once it has been executed.
+type BatchMetrics struct {
+	System   string         `json:"system"`
+	BatchID  uint64         `json:"batch_id"`
+	Tasks    int            `json:"tasks"`       // Number of tasks in the operation
+	Failed   int            `json:"failed"`      // Number of tasks that failed
+	Duration time.Duration  `json:"duration_ns"` // Wall-clock time taken by the operation
+	LockWait time.Duration  `json:"lock_wait_ns"`
+	Read     time.Duration  `json:"read_ns"`
+	Decode   time.Duration  `json:"decode_ns"`
+	Bytes    int64          `json:"bytes"`
+	Assets   []AssetMetrics `json:"assets"` // Metrics of every asset locked by the operation, slowest first
+}
+
+// CacheMetrics describes a lookup of the decoded value of an asset in a cache, such as a storage.Cache.
+type CacheMetrics struct {
+	System string `json:"system"`
+	Cache  string `json:"cache"` // Name of the cache
+	Asset  Asset  `json:"asset"`
+	Hit    bool   `json:"hit"` // Whether the cache held the value
+}
+
+// MetricsSink receives the metrics recorded by a ResourceSystem.
+//
+// Sink methods may be called concurrently from loading goroutines and must not block.
+type MetricsSink interface {
+	// AssetLoaded is called each time an AssetLock is released.
+	AssetLoaded(metrics AssetMetrics)
+
+	// BatchCompleted is called each time a LoadingOperation finishes executing.
+	BatchCompleted(metrics BatchMetrics)
+
+	// CacheLookup is called each time a cache looks up the value of an asset, as reported through
+	// RecordCacheLookup. It may be called every frame for every value in use.
+	CacheLookup(metrics CacheMetrics)
+}
+
+// SetMetrics sets the sink that receives the metrics of the ResourceSystem.
+//
+// Metrics are only recorded while a sink is set. A nil sink disables recording.
+func (rs *ResourceSystem) SetMetrics(sink MetricsSink) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.sink = sink
+	if sink != nil && rs.meters == nil {
+		rs.meters = make(map[Asset]*assetMeter)
+		rs.collectors = make(map[uint64]*batchCollector)
+	}
+}
+
+// RecordCacheLookup reports a lookup of the decoded value of an asset in the named cache to the sink of the
+// ResourceSystem. Caches holding values decoded from the assets of a ResourceSystem call it on every lookup.
+//
+// It has no effect if no sink is set.
+func (rs *ResourceSystem) RecordCacheLookup(cache string, asset Asset, hit bool) {
+	rs.mu.RLock()
+	sink := rs.sink
+	rs.mu.RUnlock()
+
+	if sink == nil {
+		return
+	}
+
+	sink.CacheLookup(CacheMetrics{
+		System: rs.name,
+		Cache:  cache,
+		Asset:  asset,
+		Hit:    hit,
+	})
+}
+
+// assetMeter accumulates the metrics of an asset while its AssetLock is held.
+type assetMeter struct {
+	metrics  AssetMetrics
+	acquired time.Time
+	mu       sync.Mutex
+}
+
+func (m *assetMeter) addRead(elapsed time.Duration, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.Read += elapsed
+	m.metrics.Bytes += int64(n)
+}
+
+func (m *assetMeter) read() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metrics.Read
+}
+
+func (m *assetMeter) addDecode(elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.Decode += elapsed
+}
+
+// meterAcquired starts metering an asset whose lock was just acquired, if a sink is set.
+func (rs *ResourceSystem) meterAcquired(lock *AssetLock, path string, wait time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.sink == nil {
+		return
+	}
+
+	lock.meter = &assetMeter{
+		metrics: AssetMetrics{
+			System:   rs.name,
+			Asset:    lock.asset,
+			Path:     path,
+			BatchID:  lock.batchID,
+			LockWait: wait,
+		},
+		acquired: time.Now(),
+	}
+	rs.meters[lock.asset] = lock.meter
+}
+
+// meterReleased stops metering an asset whose lock was released. It must be called with rs.mu held, and
+// returns the function that reports the metrics, which must be called once rs.mu is released.
+func (rs *ResourceSystem) meterReleased(lock *AssetLock) func() {
+	meter := lock.meter
+	if meter == nil {
+		return func() {}
+	}
+	lock.meter = nil
+
+	if rs.meters[lock.asset] == meter {
+		delete(rs.meters, lock.asset)
+	}
+
+	sink := rs.sink
+	collector := rs.collectors[lock.batchID]
+
+	return func() {
+		meter.mu.Lock()
+		metrics := meter.metrics
+		metrics.Held = time.Since(meter.acquired)
+		meter.mu.Unlock()
+
+		if collector != nil {
+			collector.add(metrics)
+		}
+		if sink != nil {
+			sink.AssetLoaded(metrics)
+		}
+	}
+}
+
+// meterFor returns the meter of an asset whose lock is currently held, or nil.
+func (rs *ResourceSystem) meterFor(asset Asset) *assetMeter {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.meters[asset]
+}
+
+// measureDecode runs a decoder for an asset and records the time it took, excluding reads.
+func (rs *ResourceSystem) measureDecode(asset Asset, decode func()) {
+	meter := rs.meterFor(asset)
+	if meter == nil {
+		decode()
+		return
+	}
+
+	start := time.Now()
+	read := meter.read()
+
+	decode()
+
+	meter.addDecode(time.Since(start) - (meter.read() - read))
+}
+
+// meteredFile records the time spent reading an AssetFile and the number of bytes read.
+type meteredFile struct {
+	AssetFile
+	meter *assetMeter
+}
+
+func (f *meteredFile) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := f.AssetFile.Read(p)
+	f.meter.addRead(time.Since(start), n)
+	return n, err
+}
+
+// batchCollector gathers the metrics of every asset locked by a LoadingOperation.
+type batchCollector struct {
+	rs       *ResourceSystem
+	batchIDs []uint64
+	sink     MetricsSink
+	start    time.Time
+	assets   []AssetMetrics
+	mu       sync.Mutex
+}
+
+// collectBatch starts collecting the metrics of the given batches, if a sink is set.
+func (rs *ResourceSystem) collectBatch(batchIDs ...uint64) *batchCollector {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.sink == nil {
+		return nil
+	}
+
+	collector := &batchCollector{
+		rs:       rs,
+		batchIDs: batchIDs,
+		sink:     rs.sink,
+		start:    time.Now(),
+		assets:   make([]AssetMetrics, 0),
+	}
+	for _, id := range batchIDs {
+		rs.collectors[id] = collector
+	}
+
+	return collector
+}
+
+func (c *batchCollector) add(metrics AssetMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.assets = append(c.assets, metrics)
+}
+
+// finish stops collecting and reports the summary of the batch. It is safe to call on a nil collector.
+func (c *batchCollector) finish(tasks, failed int) {
+	if c == nil {
+		return
+	}
+
+	c.rs.mu.Lock()
+	for _, id := range c.batchIDs {
+		if c.rs.collectors[id] == c {
+			delete(c.rs.collectors, id)
+		}
+	}
+	c.rs.mu.Unlock()
+
+	c.mu.Lock()
+	summary := BatchMetrics{
+		System:   c.rs.name,
+		BatchID:  c.batchIDs[0],
+		Tasks:    tasks,
+		Failed:   failed,
+		Duration: time.Since(c.start),
+		Assets:   c.assets,
+	}
+	c.mu.Unlock()
+
+	for _, asset := range summary.Assets {
+		summary.LockWait += asset.LockWait
+		summary.Read += asset.Read
+		summary.Decode += asset.Decode
+		summary.Bytes += asset.Bytes
+	}
+	slices.SortStableFunc(summary.Assets, func(a, b AssetMetrics) int {
+		return cmp.Compare(b.LockWait+b.Held, a.LockWait+a.Held)
+	})
+
+	c.sink.BatchCompleted(summary)
+}
+
+// ============================== Sinks ==============================
+
+// MultiSink returns a MetricsSink that forwards metrics to every given sink.
+func MultiSink(sinks ...MetricsSink) MetricsSink {
+	return multiSink(slices.Clone(sinks))
+}
+
+type multiSink []MetricsSink
+
+func (s multiSink) AssetLoaded(metrics AssetMetrics) {
+	for _, sink := range s {
+		sink.AssetLoaded(metrics)
+	}
+}
+
+func (s multiSink) BatchCompleted(metrics BatchMetrics) {
+	for _, sink := range s {
+		sink.BatchCompleted(metrics)
+	}
+}
+
+func (s multiSink) CacheLookup(metrics CacheMetrics) {
+	for _, sink := range s {
+		sink.CacheLookup(metrics)
+	}
+}
+
+// NewLoggerSink returns a MetricsSink that logs asset metrics and cache misses at debug level, and batch
+// summaries at info level. Cache hits are not logged.
+func NewLoggerSink(logger flinch.Logger) MetricsSink {
+	return &loggerSink{logger: logger}
+}
+
+type loggerSink struct {
+	logger flinch.Logger
+}
+
+func (s *loggerSink) AssetLoaded(m AssetMetrics) {
+	s.logger.Debug("Asset loaded",
+		"system", m.System,
+		"path", m.Path,
+		"batch", m.BatchID,
+		"lock_wait", m.LockWait,
+		"read", m.Read,
+		"decode", m.Decode,
+		"bytes", m.Bytes,
+	)
+}
+
+func (s *loggerSink) BatchCompleted(m BatchMetrics) {
+	args := []any{
+		"system", m.System,
+		"batch", m.BatchID,
+		"tasks", m.Tasks,
+		"failed", m.Failed,
+		"duration", m.Duration,
+		"lock_wait", m.LockWait,
+		"read", m.Read,
+		"decode", m.Decode,
+		"bytes", m.Bytes,
+	}
+	if len(m.Assets) > 0 {
+		args = append(args, "slowest", m.Assets[0].Path)
+	}
+	s.logger.Info("Batch completed", args...)
+}
+
+func (s *loggerSink) CacheLookup(m CacheMetrics) {
+	if m.Hit {
+		return
+	}
+	s.logger.Debug("Cache miss",
+		"system", m.System,
+		"cache", m.Cache,
+		"asset", m.Asset,
+	)
+}
+
+// MetricsReportOptions defines configuration options for a MetricsReport.
+type MetricsReportOptions struct {
+	// Batches is the number of most recent batch summaries kept by the report. Values less than one keep
+	// DefaultReportBatches summaries.
+	Batches int
+}
+
+// DefaultReportBatches is the number of batch summaries kept by a MetricsReport unless configured otherwise.
+const DefaultReportBatches = 256
+
+// AssetSummary aggregates the metrics of every time the AssetLock of an asset was held.
+type AssetSummary struct {
+	System   string        `json:"system"`
+	Asset    Asset         `json:"asset"`
+	Path     string        `json:"path"`
+	Loads    int           `json:"loads"`        // Number of times the AssetLock was released
+	LockWait time.Duration `json:"lock_wait_ns"` // Total time spent waiting to acquire the AssetLock
+	Read     time.Duration `json:"read_ns"`
+	Decode   time.Duration `json:"decode_ns"`
+	Held     time.Duration `json:"held_ns"`
+	MaxHeld  time.Duration `json:"max_held_ns"` // Longest time the AssetLock was held at once
+	Bytes    int64         `json:"bytes"`
+}
+
+// CacheSummary counts the lookups made in a cache for the assets of a ResourceSystem.
+type CacheSummary struct {
+	System string `json:"system"`
+	Cache  string `json:"cache"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// MetricsReport is a MetricsSink that aggregates the metrics it receives, for example to be shown in a
+// debug overlay or exported as a JSON report at the end of a session.
+//
+// Asset metrics and cache lookups are aggregated per asset and per cache, and only the most recent batch
+// summaries are kept, so that the memory used by the report does not grow with the length of the session.
+//
+// MetricsReport is safe for concurrent use by multiple goroutines.
+type MetricsReport struct {
+	options MetricsReportOptions
+	assets  map[reportKey]*AssetSummary
+	caches  map[reportKey]*CacheSummary
+	batches []BatchMetrics // Ring of the most recent batch summaries
+	next    int            // Index of the oldest batch summary once the ring is full
+	dropped int            // Number of batch summaries discarded from the ring
+	mu      sync.Mutex
+}
+
+type reportKey struct {
+	system string
+	cache  string
+	asset  Asset
+}
+
+// NewMetricsReport creates a new, empty MetricsReport with the given options.
+func NewMetricsReport(options MetricsReportOptions) *MetricsReport {
+	if options.Batches < 1 {
+		options.Batches = DefaultReportBatches
+	}
+
+	return &MetricsReport{
+		options: options,
+		assets:  make(map[reportKey]*AssetSummary),
+		caches:  make(map[reportKey]*CacheSummary),
+		batches: make([]BatchMetrics, 0),
+	}
+}
+
+func (r *MetricsReport) AssetLoaded(metrics AssetMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reportKey{system: metrics.System, asset: metrics.Asset}
+	summary, exists := r.assets[key]
+	if !exists {
+		summary = &AssetSummary{System: metrics.System, Asset: metrics.Asset}
+		r.assets[key] = summary
+	}
+
+	summary.Path = metrics.Path
+	summary.Loads++
+	summary.LockWait += metrics.LockWait
+	summary.Read += metrics.Read
+	summary.Decode += metrics.Decode
+	summary.Held += metrics.Held
+	summary.MaxHeld = max(summary.MaxHeld, metrics.Held)
+	summary.Bytes += metrics.Bytes
+}
+
+func (r *MetricsReport) BatchCompleted(metrics BatchMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.batches) < r.options.Batches {
+		r.batches = append(r.batches, metrics)
+		return
+	}
+
+	r.batches[r.next] = metrics
+	r.next = (r.next + 1) % len(r.batches)
+	r.dropped++
+}
+
+func (r *MetricsReport) CacheLookup(metrics CacheMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reportKey{system: metrics.System, cache: metrics.Cache}
+	summary, exists := r.caches[key]
+	if !exists {
+		summary = &CacheSummary{System: metrics.System, Cache: metrics.Cache}
+		r.caches[key] = summary
+	}
+
+	if metrics.Hit {
+		summary.Hits++
+	} else {
+		summary.Misses++
+	}
+}
+
+// Assets returns the aggregated metrics of every asset loaded so far, slowest first.
+func (r *MetricsReport) Assets() []AssetSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.assetSummaries()
+}
+
+// Caches returns the lookups counted so far for every cache, sorted by system and cache name.
+func (r *MetricsReport) Caches() []CacheSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cacheSummaries()
+}
+
+// Batches returns the most recent batch summaries, in the order they were received.
+func (r *MetricsReport) Batches() []BatchMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recentBatches()
+}
+
+// WriteJSON writes every aggregated metric and the most recent batch summaries to w as a JSON document.
+func (r *MetricsReport) WriteJSON(w io.Writer) error {
+	r.mu.Lock()
+	document := struct {
+		Assets         []AssetSummary `json:"assets"`
+		Caches         []CacheSummary `json:"caches"`
+		Batches        []BatchMetrics `json:"batches"`
+		DroppedBatches int            `json:"dropped_batches"` // Older batch summaries left out of the report
+	}{
+		Assets:         r.assetSummaries(),
+		Caches:         r.cacheSummaries(),
+		Batches:        r.recentBatches(),
+		DroppedBatches: r.dropped,
+	}
+	r.mu.Unlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write metrics report: %w", err)
+	}
+	return nil
+}
+
+// assetSummaries returns a copy of the asset summaries, slowest first. It must be called with r.mu held.
+func (r *MetricsReport) assetSummaries() []AssetSummary {
+	summaries := make([]AssetSummary, 0, len(r.assets))
+	for _, summary := range r.assets {
+		summaries = append(summaries, *summary)
+	}
+
+	slices.SortFunc(summaries, func(a, b AssetSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.LockWait+b.Held, a.LockWait+a.Held),
+			cmp.Compare(a.System, b.System),
+			cmp.Compare(a.Asset, b.Asset),
+		)
+	})
+
+	return summaries
+}
+
+// cacheSummaries returns a copy of the cache summaries, sorted by system and cache name. It must be called
+// with r.mu held.
+func (r *MetricsReport) cacheSummaries() []CacheSummary {
+	summaries := make([]CacheSummary, 0, len(r.caches))
+	for _, summary := range r.caches {
+		summaries = append(summaries, *summary)
+	}
+
+	slices.SortFunc(summaries, func(a, b CacheSummary) int {
+		return cmp.Or(cmp.Compare(a.System, b.System), cmp.Compare(a.Cache, b.Cache))
+	})
+
+	return summaries
+}
+
+// recentBatches returns a copy of the batch summaries, oldest first. It must be called with r.mu held.
+func (r *MetricsReport) recentBatches() []BatchMetrics {
+	return slices.Concat(r.batches[r.next:], r.batches[:r.next])
+}
@@ -0,0 +1,95 @@
+package resources_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestMetricsReportAggregatesAssets(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("metrics", files)
+
+	report := resources.NewMetricsReport(resources.MetricsReportOptions{})
+	rs.SetMetrics(report)
+
+	for _, asset := range []resources.Asset{assets[0], assets[1], assets[0]} {
+		lock := rs.LockAsset(resources.NewBatchID(), asset)
+		lock.Release()
+	}
+
+	summaries := report.Assets()
+	if len(summaries) != 2 {
+		t.Fatalf("got %d asset summaries, want 2", len(summaries))
+	}
+
+	loads := make(map[resources.Asset]int)
+	for _, summary := range summaries {
+		loads[summary.Asset] = summary.Loads
+		if summary.System != "metrics" || summary.Path == "" {
+			t.Errorf("got summary %+v without its system or path", summary)
+		}
+	}
+	if loads[assets[0]] != 2 || loads[assets[1]] != 1 {
+		t.Fatalf("got loads %v, want 2 and 1", loads)
+	}
+}
+
+func TestMetricsReportKeepsRecentBatches(t *testing.T) {
+	report := resources.NewMetricsReport(resources.MetricsReportOptions{Batches: 2})
+	for id := range uint64(5) {
+		report.BatchCompleted(resources.BatchMetrics{BatchID: id})
+	}
+
+	ids := make([]uint64, 0)
+	for _, batch := range report.Batches() {
+		ids = append(ids, batch.BatchID)
+	}
+	if want := []uint64{3, 4}; !slices.Equal(ids, want) {
+		t.Fatalf("got batches %v, want %v", ids, want)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := report.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var document struct {
+		Batches        []resources.BatchMetrics `json:"batches"`
+		DroppedBatches int                      `json:"dropped_batches"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Batches) != 2 || document.DroppedBatches != 3 {
+		t.Fatalf("got %d batches and %d dropped, want 2 and 3", len(document.Batches), document.DroppedBatches)
+	}
+}
+
+func TestRecordCacheLookup(t *testing.T) {
+	files, assets := newFiles(1)
+	rs := resourcestest.NewSystem("metrics", files)
+
+	// Lookups are ignored until a sink is set.
+	rs.RecordCacheLookup("values", assets[0], false)
+
+	report := resources.NewMetricsReport(resources.MetricsReportOptions{})
+	rs.SetMetrics(report)
+
+	rs.RecordCacheLookup("values", assets[0], true)
+	rs.RecordCacheLookup("values", assets[0], true)
+	rs.RecordCacheLookup("values", assets[0], false)
+	rs.RecordCacheLookup("others", assets[0], false)
+
+	want := []resources.CacheSummary{
+		{System: "metrics", Cache: "others", Misses: 1},
+		{System: "metrics", Cache: "values", Hits: 2, Misses: 1},
+	}
+	if got := report.Caches(); !slices.Equal(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
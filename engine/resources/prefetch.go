@@ -0,0 +1,383 @@
+package resources
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/adm87/flinch/engine/flinch"
+)
+
+// ============================== Prefetching ==============================
+
+// PrefetchOptions defines configuration options for a Prefetcher.
+type PrefetchOptions struct {
+	// Workers is the number of background goroutines loading assets. Values less than one use a single worker.
+	Workers int
+
+	// BytesPerFrame is the number of bytes of asset files the prefetcher may start loading between two calls
+	// to Frame. An asset larger than the budget is started once the full budget is available, and the excess
+	// is carried over to the following frames.
+	//
+	// A budget of zero or less disables the limit.
+	BytesPerFrame int64
+
+	// Loader returns the task that loads a single asset. If nil, assets are loaded with the Loaders registered
+	// for their extensions, as with NewLoader.
+	Loader func(asset Asset) LoadingTask
+}
+
+// Prefetcher loads assets on background goroutines ahead of the time they are needed, such as the assets of
+// the next level while the current one is still being played.
+//
+// Queued assets are loaded in order of decreasing priority, and in the order they were queued for equal
+// priorities. Loading is throttled by a bytes-per-frame budget so that prefetching does not compete with
+// the game loop, except for assets that have been promoted.
+//
+// Prefetcher is safe for concurrent use by multiple goroutines.
+type Prefetcher struct {
+	rs      *ResourceSystem
+	options PrefetchOptions
+
+	queue   prefetchQueue
+	items   map[Asset]*prefetchItem // Assets that are queued or being loaded
+	seq     uint64
+	budget  int64
+	stopped bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+type prefetchItem struct {
+	asset     Asset
+	priority  int
+	immediate bool   // Whether the asset was promoted past the queue and the budget
+	seq       uint64 // Order in which the asset was queued
+	size      int64  // Estimated size of the asset file in bytes
+	index     int    // Index within the queue, or -1 once the asset is being loaded
+
+	done   chan struct{} // Closed once the asset has been loaded
+	err    error         // Error returned while loading the asset
+	cancel context.CancelFunc
+}
+
+// Prefetch starts a Prefetcher for the ResourceSystem.
+//
+// The prefetcher runs until the context is cancelled or Stop is called.
+func (rs *ResourceSystem) Prefetch(ctx *flinch.Context, options PrefetchOptions) *Prefetcher {
+	cancelCtx, cancel := context.WithCancel(ctx)
+
+	p := &Prefetcher{
+		rs:      rs,
+		options: options,
+		items:   make(map[Asset]*prefetchItem),
+		budget:  options.BytesPerFrame,
+		cancel:  cancel,
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	if p.options.Loader == nil {
+		p.options.Loader = func(asset Asset) LoadingTask {
+			return NewLoader(asset)
+		}
+	}
+
+	context.AfterFunc(cancelCtx, p.stop)
+
+	workerCtx := ctx.WithContext(cancelCtx)
+	for range max(1, options.Workers) {
+		workerBatchID := NewBatchID()
+		p.wg.Go(func() {
+			p.work(workerCtx, workerBatchID)
+		})
+	}
+
+	return p
+}
+
+// Enqueue queues assets to be loaded in the background with the given priority.
+//
+// Assets that are already queued keep their place if their priority is not raised. Assets that are being
+// loaded are not queued again.
+func (p *Prefetcher) Enqueue(priority int, assets ...Asset) {
+	sizes := make([]int64, len(assets))
+	for i, asset := range assets {
+		sizes[i] = p.rs.assetSize(asset)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+
+	for i, asset := range assets {
+		if item, exists := p.items[asset]; exists {
+			if item.index >= 0 && priority > item.priority {
+				item.priority = priority
+				heap.Fix(&p.queue, item.index)
+			}
+			continue
+		}
+
+		p.seq++
+		item := &prefetchItem{
+			asset:    asset,
+			priority: priority,
+			seq:      p.seq,
+			size:     sizes[i],
+			done:     make(chan struct{}),
+		}
+		p.items[asset] = item
+		heap.Push(&p.queue, item)
+	}
+
+	p.cond.Broadcast()
+}
+
+// Promote moves queued assets ahead of every other queued asset and exempts them from the bytes-per-frame
+// budget, so that they are loaded as soon as a worker is available.
+//
+// Promote reports whether any of the assets was still queued.
+func (p *Prefetcher) Promote(assets ...Asset) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	promoted := false
+	for _, asset := range assets {
+		if item, exists := p.items[asset]; exists && item.index >= 0 {
+			item.immediate = true
+			heap.Fix(&p.queue, item.index)
+			promoted = true
+		}
+	}
+
+	if promoted {
+		p.cond.Broadcast()
+	}
+
+	return promoted
+}
+
+// Cancel removes assets from the queue, and cancels the context of those being loaded.
+func (p *Prefetcher) Cancel(assets ...Asset) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, asset := range assets {
+		p.cancelItem(asset)
+	}
+}
+
+// CancelAll removes every asset from the queue, and cancels the context of those being loaded.
+func (p *Prefetcher) CancelAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for asset := range p.items {
+		p.cancelItem(asset)
+	}
+}
+
+// Pending returns the number of assets that are queued or being loaded.
+func (p *Prefetcher) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.items)
+}
+
+// Frame refills the bytes-per-frame budget. It should be called once per frame from the game loop.
+func (p *Prefetcher) Frame() {
+	if p.options.BytesPerFrame <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.budget = min(p.budget+p.options.BytesPerFrame, p.options.BytesPerFrame)
+	p.cond.Broadcast()
+}
+
+// Stop cancels every queued and in-progress prefetch and waits for the workers to exit.
+func (p *Prefetcher) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// NewLoader creates a new LoadingTask for a foreground LoadingOperation that needs the specified assets now.
+//
+// Assets still queued for prefetching are taken out of the queue and loaded immediately by the task itself.
+// Assets already being prefetched are waited for, and loaded by the task if their prefetch failed or was
+// cancelled. Any other asset is loaded by the task as usual.
+func (p *Prefetcher) NewLoader(assets ...Asset) LoadingTask {
+	return func(ctx *flinch.Context, rs *ResourceSystem, batchID uint64) error {
+		for _, asset := range assets {
+			p.mu.Lock()
+			item, exists := p.items[asset]
+			if exists && item.index >= 0 {
+				heap.Remove(&p.queue, item.index)
+				delete(p.items, asset)
+				exists = false
+			}
+			p.mu.Unlock()
+
+			if exists {
+				select {
+				case <-item.done:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if item.err == nil {
+					continue
+				}
+			}
+
+			if err := p.options.Loader(asset)(ctx, rs, batchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// work loads queued assets until the prefetcher is stopped.
+func (p *Prefetcher) work(ctx *flinch.Context, batchID uint64) {
+	for {
+		p.mu.Lock()
+		for !p.stopped && !p.ready() {
+			p.cond.Wait()
+		}
+		if p.stopped {
+			p.mu.Unlock()
+			return
+		}
+
+		item := heap.Pop(&p.queue).(*prefetchItem)
+		if !item.immediate && p.options.BytesPerFrame > 0 {
+			p.budget -= item.size
+		}
+
+		itemCtx, cancel := context.WithCancel(ctx)
+		item.cancel = cancel
+		p.mu.Unlock()
+
+		err := p.options.Loader(item.asset)(ctx.WithContext(itemCtx), p.rs, batchID)
+		cancel()
+
+		p.mu.Lock()
+		item.err = err
+		if p.items[item.asset] == item {
+			delete(p.items, item.asset)
+		}
+		close(item.done)
+		p.mu.Unlock()
+	}
+}
+
+// ready reports whether the next queued asset may be loaded. It must be called with p.mu held.
+func (p *Prefetcher) ready() bool {
+	if len(p.queue) == 0 {
+		return false
+	}
+
+	next := p.queue[0]
+	if next.immediate || p.options.BytesPerFrame <= 0 {
+		return true
+	}
+
+	// Assets larger than a frame's budget wait for the full budget, and the excess is then carried over.
+	return p.budget >= min(next.size, p.options.BytesPerFrame)
+}
+
+// cancelItem removes a queued asset or cancels an asset being loaded. It must be called with p.mu held.
+func (p *Prefetcher) cancelItem(asset Asset) {
+	item, exists := p.items[asset]
+	if !exists {
+		return
+	}
+
+	if item.index >= 0 {
+		heap.Remove(&p.queue, item.index)
+		delete(p.items, asset)
+		return
+	}
+
+	item.cancel()
+}
+
+func (p *Prefetcher) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	for asset := range p.items {
+		p.cancelItem(asset)
+	}
+	p.cond.Broadcast()
+}
+
+// assetSize estimates the size of an asset file from its digest, or from the file itself.
+func (rs *ResourceSystem) assetSize(asset Asset) int64 {
+	layer, ref, err := rs.resolve(asset)
+	if err != nil {
+		return 0
+	}
+
+	rs.mu.RLock()
+	digest, exists := rs.options.Digests[ref.id]
+	rs.mu.RUnlock()
+
+	if exists && !ref.overridden {
+		return digest.Size
+	}
+
+	info, err := layer.stat(ref)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// prefetchQueue is a heap of queued assets, ordering promoted assets first, then by decreasing
+// priority and increasing queue order.
+type prefetchQueue []*prefetchItem
+
+func (q prefetchQueue) Len() int { return len(q) }
+
+func (q prefetchQueue) Less(i, j int) bool {
+	if q[i].immediate != q[j].immediate {
+		return q[i].immediate
+	}
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q prefetchQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *prefetchQueue) Push(x any) {
+	item := x.(*prefetchItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *prefetchQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	item.index = -1
+	*q = old[:len(old)-1]
+	return item
+}
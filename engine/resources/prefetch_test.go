@@ -0,0 +1,166 @@
+package resources_test
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+// recorder records the assets loaded by a Prefetcher, in the order they were started.
+type recorder struct {
+	mu      sync.Mutex
+	started []resources.Asset
+	loaded  chan resources.Asset
+	gate    map[resources.Asset]chan struct{} // Assets whose loading blocks until the channel is closed
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		loaded: make(chan resources.Asset, 64),
+		gate:   make(map[resources.Asset]chan struct{}),
+	}
+}
+
+func (r *recorder) load(asset resources.Asset) resources.LoadingTask {
+	return func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+		r.mu.Lock()
+		r.started = append(r.started, asset)
+		gate := r.gate[asset]
+		r.mu.Unlock()
+
+		if gate != nil {
+			<-gate
+		}
+		r.loaded <- asset
+		return nil
+	}
+}
+
+func (r *recorder) order() []resources.Asset {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.started)
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-r.loaded:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for prefetched assets")
+		}
+	}
+}
+
+func (r *recorder) idle(t *testing.T) {
+	t.Helper()
+	select {
+	case asset := <-r.loaded:
+		t.Fatalf("asset %d loaded without budget", asset)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestPrefetchQueueOrder(t *testing.T) {
+	files, assets := newFiles(6)
+	rs := resourcestest.NewSystem("prefetch", files)
+
+	r := newRecorder()
+	blocker := make(chan struct{})
+	r.gate[assets[0]] = blocker
+
+	p := rs.Prefetch(newContext(), resources.PrefetchOptions{Workers: 1, Loader: r.load})
+	defer p.Stop()
+
+	// The single worker is held by the first asset while the others are queued.
+	p.Enqueue(0, assets[0])
+	for len(r.order()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Enqueue(1, assets[1])
+	p.Enqueue(5, assets[2], assets[3])
+	p.Enqueue(0, assets[4])
+	p.Enqueue(3, assets[5])
+	p.Enqueue(4, assets[1]) // Raises the priority of a queued asset
+
+	if !p.Promote(assets[4]) {
+		t.Fatal("queued asset not promoted")
+	}
+	p.Cancel(assets[3])
+
+	close(blocker)
+	r.wait(t, 5)
+
+	// Promoted assets first, then by decreasing priority and queue order; cancelled assets are never loaded.
+	want := []resources.Asset{assets[0], assets[4], assets[2], assets[1], assets[5]}
+	if got := r.order(); !slices.Equal(got, want) {
+		t.Fatalf("got load order %v, want %v", got, want)
+	}
+	if pending := p.Pending(); pending != 0 {
+		t.Fatalf("got %d pending assets, want 0", pending)
+	}
+}
+
+func TestPrefetchBytesPerFrame(t *testing.T) {
+	sizes := []int{4, 4, 25, 4}
+	files := make(map[string][]byte, len(sizes))
+	assets := make([]resources.Asset, len(sizes))
+	for i, size := range sizes {
+		assetPath := fmt.Sprintf("assets/file%02d.bin", i)
+		files[assetPath] = bytes.Repeat([]byte{'a'}, size)
+		assets[i] = resourcestest.AssetOf(assetPath)
+	}
+	rs := resourcestest.NewSystem("prefetch", files)
+
+	r := newRecorder()
+	p := rs.Prefetch(newContext(), resources.PrefetchOptions{Workers: 1, BytesPerFrame: 10, Loader: r.load})
+	defer p.Stop()
+
+	p.Enqueue(0, assets...)
+
+	// The first two assets fit in the budget, leaving 2 bytes.
+	r.wait(t, 2)
+	r.idle(t)
+
+	// The large asset starts once the full budget is available, and its excess is carried over.
+	p.Frame()
+	r.wait(t, 1)
+
+	p.Frame()
+	r.idle(t)
+
+	p.Frame()
+	r.wait(t, 1)
+
+	if got := r.order(); !slices.Equal(got, assets) {
+		t.Fatalf("got load order %v, want %v", got, assets)
+	}
+}
+
+func TestPrefetchPromoteBypassesBudget(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("prefetch", files)
+
+	r := newRecorder()
+	p := rs.Prefetch(newContext(), resources.PrefetchOptions{Workers: 1, BytesPerFrame: 1, Loader: r.load})
+	defer p.Stop()
+
+	// The first asset uses up the budget, so the second waits for the next frame.
+	p.Enqueue(0, assets...)
+	r.wait(t, 1)
+	r.idle(t)
+
+	if !p.Promote(assets[1]) {
+		t.Fatal("queued asset not promoted")
+	}
+	r.wait(t, 1)
+}
@@ -0,0 +1,187 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/adm87/flinch/engine/flinch"
+)
+
+// ============================== Loader Registry ==============================
+
+var (
+	// ErrNoLoader is returned when loading an asset whose extension has no registered Loader.
+	ErrNoLoader = errors.New("no loader registered for asset")
+
+	// ErrTypeMismatch is returned by Load when the registered Loader for an asset produces a different type.
+	ErrTypeMismatch = errors.New("asset loader produces a different type")
+
+	loaders   = make(map[string]*registration)
+	loadersMu sync.RWMutex
+)
+
+// Loader describes how assets of a given type are decoded and where decoded values are kept.
+type Loader[T any] struct {
+	// Decode reads and decodes an asset. It is called with the lock for the asset held.
+	Decode func(ctx context.Context, rs *ResourceSystem, asset Asset) (T, error)
+
+	// Store keeps a decoded value, typically in a cache. It may be nil if values are only returned by Load.
+	Store func(rs *ResourceSystem, asset Asset, value T)
+
+	// Cached returns a previously stored value, if any. When it reports a value, the asset is not decoded
+	// again. It may be nil if values are not cached. Cached is also called with the lock for the asset held,
+	// so it must not load the asset itself, for example to reload an evicted value.
+	Cached func(rs *ResourceSystem, asset Asset) (T, bool)
+
+	// Retain is called with the context of every load before the asset is taken from the cache or decoded,
+	// so that the cache can record the value as used by the caller, such as in a scope carried by the
+	// context. It may be nil.
+	Retain func(ctx context.Context, rs *ResourceSystem, asset Asset)
+}
+
+type registration struct {
+	typ  reflect.Type
+	load func(ctx context.Context, rs *ResourceSystem, asset Asset, batchID uint64) (any, error)
+}
+
+// Register registers a Loader for assets with the given file extensions.
+//
+// Extensions include the leading dot, e.g. ".png". Registering a loader for an extension that already
+// has one replaces it. Loaders are typically registered from the init function of the package that
+// caches the values they produce.
+func Register[T any](loader Loader[T], exts ...string) {
+	reg := &registration{
+		typ: reflect.TypeFor[T](),
+		load: func(ctx context.Context, rs *ResourceSystem, asset Asset, batchID uint64) (any, error) {
+			return load(ctx, rs, asset, batchID, loader)
+		},
+	}
+
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+
+	for _, ext := range exts {
+		loaders[strings.ToLower(ext)] = reg
+	}
+}
+
+// Load returns the value of type T for the asset, decoding it with the Loader registered for its extension
+// unless the loader already holds a cached value.
+//
+// Load acquires the lock for the asset under a new batch ID. If no loader is registered for the asset's
+// extension, an *AssetError wrapping ErrNoLoader is returned; if the registered loader produces a type
+// other than T, one wrapping ErrTypeMismatch is returned.
+func Load[T any](ctx context.Context, rs *ResourceSystem, asset Asset) (T, error) {
+	var zero T
+
+	reg, err := rs.registration(asset)
+	if err != nil {
+		return zero, err
+	}
+
+	want := reflect.TypeFor[T]()
+	if reg.typ != want && (want.Kind() != reflect.Interface || !reg.typ.Implements(want)) {
+		return zero, rs.assetError("load", asset, fmt.Errorf("%w: %s, not %s", ErrTypeMismatch, reg.typ, want))
+	}
+
+	value, err := reg.load(ctx, rs, asset, NewBatchID())
+	if err != nil {
+		return zero, err
+	}
+
+	typed, _ := value.(T)
+	return typed, nil
+}
+
+// NewLoader creates a new LoadingTask that loads the specified assets with the Loaders registered for their
+// extensions. Assets already cached by their loader are not decoded again.
+func NewLoader(assets ...Asset) LoadingTask {
+	return func(ctx *flinch.Context, rs *ResourceSystem, batchID uint64) error {
+		for _, asset := range assets {
+			reg, err := rs.registration(asset)
+			if err != nil {
+				return err
+			}
+			if _, err := reg.load(ctx, rs, asset, batchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CreateAssetBatch creates a new LoadingOperation that loads each of the given assets with the Loader
+// registered for its extension, as a separate task.
+func (rs *ResourceSystem) CreateAssetBatch(assets ...Asset) *LoadingOperation {
+	tasks := make([]LoadingTask, len(assets))
+	for i, asset := range assets {
+		tasks[i] = NewLoader(asset)
+	}
+	return rs.CreateBatch(tasks...)
+}
+
+// registration returns the registered loader for the extension of the asset.
+func (rs *ResourceSystem) registration(asset Asset) (*registration, error) {
+	assetPath, exists := rs.Path(asset)
+	if !exists {
+		return nil, rs.assetError("load", asset, ErrUnknownAsset)
+	}
+
+	ext := strings.ToLower(path.Ext(assetPath))
+
+	loadersMu.RLock()
+	reg, exists := loaders[ext]
+	loadersMu.RUnlock()
+
+	if !exists {
+		return nil, rs.assetError("load", asset, fmt.Errorf("%w: extension %q", ErrNoLoader, ext))
+	}
+
+	return reg, nil
+}
+
+// load loads an asset with the loader under the given batch ID, returning the cached value if there is one.
+func load[T any](ctx context.Context, rs *ResourceSystem, asset Asset, batchID uint64, loader Loader[T]) (T, error) {
+	if loader.Retain != nil {
+		loader.Retain(ctx, rs, asset)
+	}
+
+	if loader.Cached != nil {
+		if value, exists := loader.Cached(rs, asset); exists {
+			return value, nil
+		}
+	}
+
+	lock, err := rs.LockAssetContext(ctx, batchID, asset)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer lock.Release()
+
+	// Another batch may have loaded the asset while this one was waiting for the lock.
+	if loader.Cached != nil {
+		if value, exists := loader.Cached(rs, asset); exists {
+			return value, nil
+		}
+	}
+
+	var value T
+	rs.measureDecode(asset, func() {
+		value, err = loader.Decode(ctx, rs, asset)
+	})
+	if err != nil {
+		return value, err
+	}
+
+	if loader.Store != nil {
+		loader.Store(rs, asset, value)
+	}
+
+	return value, nil
+}
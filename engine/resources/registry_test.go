@@ -0,0 +1,167 @@
+package resources_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+// textLoader is a Loader of text assets backed by an in-memory cache.
+type textLoader struct {
+	decodes atomic.Int64
+	values  map[resources.Asset]string
+	mu      sync.Mutex
+}
+
+// registerText registers a new textLoader for ".txt" assets, replacing the loader of any previous test.
+func registerText() *textLoader {
+	l := &textLoader{values: make(map[resources.Asset]string)}
+	resources.Register(resources.Loader[string]{
+		Decode: l.decode,
+		Store:  l.store,
+		Cached: l.cached,
+	}, ".txt")
+	return l
+}
+
+func (l *textLoader) decode(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (string, error) {
+	l.decodes.Add(1)
+	data, err := rs.ReadBytesContext(ctx, asset)
+	return string(data), err
+}
+
+func (l *textLoader) store(rs *resources.ResourceSystem, asset resources.Asset, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values[asset] = value
+}
+
+func (l *textLoader) cached(rs *resources.ResourceSystem, asset resources.Asset) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	value, exists := l.values[asset]
+	return value, exists
+}
+
+func newTextFiles() (map[string][]byte, []resources.Asset) {
+	return resourcestest.Files("text/a.txt", "text/b.txt", "text/c.txt", "data/d.unknown")
+}
+
+func TestLoadDecodesConcurrentLoadsOnce(t *testing.T) {
+	loader := registerText()
+	files, assets := newTextFiles()
+	rs := resourcestest.NewSystem("registry", files)
+
+	faults := resourcestest.NewFaultFS(resourcestest.NewFS(files), 1)
+	faults.Inject(resourcestest.Delay("", time.Millisecond))
+	rs.SetFileSystem(faults)
+
+	wg := sync.WaitGroup{}
+	for range 8 {
+		wg.Go(func() {
+			value, err := resources.Load[string](context.Background(), rs, assets[0])
+			if err != nil {
+				t.Error(err)
+			} else if value != "text/a.txt" {
+				t.Errorf("got %q, want text/a.txt", value)
+			}
+		})
+	}
+	wg.Wait()
+
+	if decodes := loader.decodes.Load(); decodes != 1 {
+		t.Fatalf("decoded %d times, want 1", decodes)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	registerText()
+	files, assets := newTextFiles()
+	rs := resourcestest.NewSystem("registry", files)
+
+	_, err := resources.Load[string](context.Background(), rs, assets[3])
+	if !errors.Is(err, resources.ErrNoLoader) {
+		t.Fatalf("got error %v, want ErrNoLoader", err)
+	}
+	var assetErr *resources.AssetError
+	if !errors.As(err, &assetErr) || assetErr.Path != "data/d.unknown" {
+		t.Fatalf("got error %v, want an AssetError for data/d.unknown", err)
+	}
+
+	if _, err := resources.Load[[]byte](context.Background(), rs, assets[0]); !errors.Is(err, resources.ErrTypeMismatch) {
+		t.Fatalf("got error %v, want ErrTypeMismatch", err)
+	}
+	if _, err := resources.Load[any](context.Background(), rs, assets[0]); err != nil {
+		t.Fatalf("got error %v loading into an interface the value implements", err)
+	}
+
+	if _, err := resources.Load[string](context.Background(), rs, resources.Asset(0)); !errors.Is(err, resources.ErrUnknownAsset) {
+		t.Fatalf("got error %v, want ErrUnknownAsset", err)
+	}
+}
+
+func TestLoadRetriesAfterFailure(t *testing.T) {
+	loader := registerText()
+	files, assets := newTextFiles()
+	rs := resourcestest.NewSystem("registry", files)
+
+	fault := resourcestest.FailOpen("text/a.txt", nil)
+	fault.Count = 1
+	faults := resourcestest.NewFaultFS(resourcestest.NewFS(files), 1)
+	faults.Inject(fault)
+	rs.SetFileSystem(faults)
+
+	if _, err := resources.Load[string](context.Background(), rs, assets[0]); !errors.Is(err, resourcestest.ErrInjected) {
+		t.Fatalf("got error %v, want the injected error", err)
+	}
+	if _, exists := loader.cached(rs, assets[0]); exists {
+		t.Fatal("failed load stored a value")
+	}
+
+	value, err := resources.Load[string](context.Background(), rs, assets[0])
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if value != "text/a.txt" {
+		t.Fatalf("got %q, want text/a.txt", value)
+	}
+	if opens := faults.Opens("text/a.txt"); opens != 2 {
+		t.Fatalf("got %d opens, want 2", opens)
+	}
+}
+
+func TestExecuteAllReportsAssetErrors(t *testing.T) {
+	registerText()
+	files, assets := newTextFiles()
+	rs := resourcestest.NewSystem("registry", files)
+
+	faults := resourcestest.NewFaultFS(resourcestest.NewFS(files), 1)
+	faults.Inject(resourcestest.FailOpen("text/b.txt", nil))
+	rs.SetFileSystem(faults)
+
+	report := rs.CreateAssetBatch(assets...).ExecuteAll(newContext())
+
+	if report.Tasks != 4 {
+		t.Fatalf("got %d tasks, want 4", report.Tasks)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].Task != 1 || report.Failures[1].Task != 3 {
+		t.Fatalf("got failures %v, want tasks 1 and 3", report.Failures)
+	}
+	if !errors.Is(report.Err(), resourcestest.ErrInjected) || !errors.Is(report.Err(), resources.ErrNoLoader) {
+		t.Fatalf("got error %v, want the injected error and ErrNoLoader", report.Err())
+	}
+
+	failed := make([]resources.Asset, 0)
+	for _, assetErr := range report.AssetErrors() {
+		failed = append(failed, assetErr.Asset)
+	}
+	if len(failed) != 2 || failed[0] != assets[1] || failed[1] != assets[3] {
+		t.Fatalf("got asset errors for %v, want %v and %v", failed, assets[1], assets[3])
+	}
+}
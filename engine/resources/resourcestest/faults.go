@@ -0,0 +1,241 @@
+package resourcestest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error returned by faults that are not given an error of their own.
+var ErrInjected = errors.New("resourcestest: injected fault")
+
+// Fault describes a failure injected into the files opened from a FaultFS.
+//
+// A fault applies to the files whose path matches its pattern. Faults combine: a file may be both slow
+// and truncated, for example. The helper functions FailOpen, FailRead, Truncate, Delay and PartialReads
+// create the common faults.
+type Fault struct {
+	// Match is a path.Match pattern of the files the fault applies to. An empty pattern matches every file.
+	Match string
+
+	// Latency is added to Open and to every Read of the file.
+	Latency time.Duration
+
+	// OpenErr is returned by Open instead of the file.
+	OpenErr error
+
+	// ReadErr is returned by Read once ReadAfter bytes of the file have been read. Returning io.EOF
+	// simulates a truncated file.
+	ReadErr   error
+	ReadAfter int64
+
+	// MaxRead limits the number of bytes returned by each Read, simulating partial reads.
+	MaxRead int
+
+	// Probability is the chance that the fault applies to a given open of a matching file. Values of zero
+	// or less, or of one or more, apply the fault to every open.
+	Probability float64
+
+	// Count is the number of opens the fault applies to, after which it is removed. Zero applies the fault
+	// to every open.
+	Count int
+}
+
+// FailOpen returns a Fault that fails opening the matching files with err, or ErrInjected if err is nil.
+func FailOpen(match string, err error) Fault {
+	return Fault{Match: match, OpenErr: firstErr(err, ErrInjected)}
+}
+
+// FailRead returns a Fault that fails reading the matching files with err, or ErrInjected if err is nil,
+// once after bytes have been read.
+func FailRead(match string, after int64, err error) Fault {
+	return Fault{Match: match, ReadErr: firstErr(err, ErrInjected), ReadAfter: after}
+}
+
+// Truncate returns a Fault that ends the matching files after size bytes.
+func Truncate(match string, size int64) Fault {
+	return Fault{Match: match, ReadErr: io.EOF, ReadAfter: size}
+}
+
+// Delay returns a Fault that delays opening and every read of the matching files by latency.
+func Delay(match string, latency time.Duration) Fault {
+	return Fault{Match: match, Latency: latency}
+}
+
+// PartialReads returns a Fault that returns at most n bytes from each read of the matching files.
+func PartialReads(match string, n int) Fault {
+	return Fault{Match: match, MaxRead: n}
+}
+
+// FaultFS wraps a filesystem and injects faults into the files opened from it.
+//
+// Random faults are drawn from a generator seeded when the FaultFS is created, so a test that opens files
+// in a deterministic order observes the same faults on every run.
+//
+// FaultFS only implements fs.FS: wrapping a filesystem that looks up assets by identifier, such as a pack,
+// makes the ResourceSystem open its assets by path instead.
+//
+// FaultFS is safe for concurrent use by multiple goroutines.
+type FaultFS struct {
+	fsys   fs.FS
+	rand   *rand.Rand
+	faults []*injectedFault
+	opens  map[string]int
+
+	mu sync.Mutex
+}
+
+// NewFaultFS wraps the filesystem, drawing random faults from a generator with the given seed.
+func NewFaultFS(fsys fs.FS, seed uint64) *FaultFS {
+	return &FaultFS{
+		fsys:  fsys,
+		rand:  rand.New(rand.NewPCG(seed, seed)),
+		opens: make(map[string]int),
+	}
+}
+
+// Inject adds faults to the filesystem.
+func (f *FaultFS) Inject(faults ...Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, fault := range faults {
+		f.faults = append(f.faults, &injectedFault{Fault: fault, remaining: fault.Count})
+	}
+}
+
+// Clear removes every fault from the filesystem.
+func (f *FaultFS) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// Opens returns the number of times the named file has been opened, including failed opens.
+func (f *FaultFS) Opens(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opens[name]
+}
+
+// Open opens the named file, applying the faults that match it.
+func (f *FaultFS) Open(name string) (fs.File, error) {
+	fault := f.apply(name)
+
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+	if fault.OpenErr != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fault.OpenErr}
+	}
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if fault.ReadErr == nil && fault.MaxRead == 0 && fault.Latency == 0 {
+		return file, nil
+	}
+
+	// Faulty files do not implement io.Seeker, so that every read goes through the fault.
+	return &faultFile{file: file, name: name, fault: fault}, nil
+}
+
+// apply records an open of the named file and returns the combination of the faults that apply to it.
+func (f *FaultFS) apply(name string) Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.opens[name]++
+
+	combined := Fault{}
+	for _, fault := range f.faults {
+		if fault.Match != "" {
+			if matched, _ := path.Match(fault.Match, name); !matched {
+				continue
+			}
+		}
+		if fault.Probability > 0 && fault.Probability < 1 && f.rand.Float64() >= fault.Probability {
+			continue
+		}
+
+		combined.Latency += fault.Latency
+		combined.OpenErr = firstErr(combined.OpenErr, fault.OpenErr)
+		if fault.ReadErr != nil && (combined.ReadErr == nil || fault.ReadAfter < combined.ReadAfter) {
+			combined.ReadErr = fault.ReadErr
+			combined.ReadAfter = fault.ReadAfter
+		}
+		if fault.MaxRead > 0 && (combined.MaxRead == 0 || fault.MaxRead < combined.MaxRead) {
+			combined.MaxRead = fault.MaxRead
+		}
+
+		if fault.Count > 0 {
+			fault.remaining--
+		}
+	}
+
+	f.faults = slices.DeleteFunc(f.faults, func(fault *injectedFault) bool {
+		return fault.Count > 0 && fault.remaining == 0
+	})
+
+	return combined
+}
+
+type injectedFault struct {
+	Fault
+	remaining int // Opens left before a counted fault is removed
+}
+
+type faultFile struct {
+	file  fs.File
+	name  string
+	fault Fault
+	read  int64
+}
+
+func (f *faultFile) Stat() (fs.FileInfo, error) { return f.file.Stat() }
+func (f *faultFile) Close() error               { return f.file.Close() }
+
+func (f *faultFile) Read(p []byte) (int, error) {
+	if f.fault.Latency > 0 {
+		time.Sleep(f.fault.Latency)
+	}
+
+	if f.fault.ReadErr != nil {
+		remaining := f.fault.ReadAfter - f.read
+		if remaining <= 0 {
+			return 0, f.readErr()
+		}
+		p = p[:min(int64(len(p)), remaining)]
+	}
+	if f.fault.MaxRead > 0 {
+		p = p[:min(len(p), f.fault.MaxRead)]
+	}
+
+	n, err := f.file.Read(p)
+	f.read += int64(n)
+	return n, err
+}
+
+func (f *faultFile) readErr() error {
+	if f.fault.ReadErr == io.EOF {
+		return io.EOF
+	}
+	return &fs.PathError{Op: "read", Path: f.name, Err: f.fault.ReadErr}
+}
+
+// firstErr returns the first of its arguments that is not nil.
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
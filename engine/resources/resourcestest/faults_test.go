@@ -0,0 +1,177 @@
+package resourcestest_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+var data = []byte("0123456789abcdefghij")
+
+func newFaultFS(seed uint64, faults ...resourcestest.Fault) *resourcestest.FaultFS {
+	fsys := resourcestest.NewFaultFS(resourcestest.NewFS(map[string][]byte{
+		"assets/a.bin": data,
+		"assets/b.bin": data,
+	}), seed)
+	fsys.Inject(faults...)
+	return fsys
+}
+
+func TestFaultCount(t *testing.T) {
+	fault := resourcestest.FailOpen("assets/a.bin", nil)
+	fault.Count = 2
+	fsys := newFaultFS(1, fault)
+
+	for i := range 3 {
+		file, err := fsys.Open("assets/a.bin")
+		if i < 2 && !errors.Is(err, resourcestest.ErrInjected) {
+			t.Fatalf("open %d: got error %v, want the injected error", i, err)
+		}
+		if i == 2 {
+			if err != nil {
+				t.Fatalf("open %d: got error %v after the fault was used up", i, err)
+			}
+			file.Close()
+		}
+	}
+
+	// Files not matching the pattern are unaffected and do not use up the fault.
+	file, err := fsys.Open("assets/b.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if opens := fsys.Opens("assets/a.bin"); opens != 3 {
+		t.Fatalf("got %d opens, want 3", opens)
+	}
+}
+
+func TestFaultProbabilityIsSeeded(t *testing.T) {
+	outcomes := func(seed uint64) []bool {
+		fault := resourcestest.FailOpen("", nil)
+		fault.Probability = 0.5
+		fsys := newFaultFS(seed, fault)
+
+		failed := make([]bool, 64)
+		for i := range failed {
+			file, err := fsys.Open("assets/a.bin")
+			failed[i] = err != nil
+			if file != nil {
+				file.Close()
+			}
+		}
+		return failed
+	}
+
+	first := outcomes(7)
+	if !slices.Equal(first, outcomes(7)) {
+		t.Fatal("faults drawn with the same seed differ")
+	}
+	if !slices.Contains(first, true) || !slices.Contains(first, false) {
+		t.Fatalf("got outcomes %v, want both failures and successes", first)
+	}
+	if slices.Equal(first, outcomes(8)) {
+		t.Fatal("faults drawn with different seeds are identical")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	fsys := newFaultFS(1, resourcestest.Truncate("assets/*", 5))
+
+	got, err := fs.ReadFile(fsys, "assets/a.bin")
+	if err != nil {
+		t.Fatalf("got error %v, want a clean end of file", err)
+	}
+	if !bytes.Equal(got, data[:5]) {
+		t.Fatalf("got %q, want %q", got, data[:5])
+	}
+}
+
+func TestFailRead(t *testing.T) {
+	readErr := errors.New("disk error")
+	fsys := newFaultFS(1, resourcestest.FailRead("assets/a.bin", 8, readErr))
+
+	file, err := fsys.Open("assets/a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	got, err := io.ReadAll(file)
+	if !errors.Is(err, readErr) {
+		t.Fatalf("got error %v, want the read error", err)
+	}
+	if !bytes.Equal(got, data[:8]) {
+		t.Fatalf("got %q before the error, want %q", got, data[:8])
+	}
+}
+
+func TestPartialReads(t *testing.T) {
+	fsys := newFaultFS(1, resourcestest.PartialReads("", 3))
+
+	file, err := fsys.Open("assets/a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	got := make([]byte, 0, len(data))
+	buf := make([]byte, len(data))
+	for {
+		n, err := file.Read(buf)
+		if n > 3 {
+			t.Fatalf("read %d bytes at once, want at most 3", n)
+		}
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q, want %q", got, data)
+	}
+}
+
+func TestNewSystem(t *testing.T) {
+	rs := resourcestest.NewSystem("test", map[string][]byte{"assets/a.bin": data})
+
+	asset := resourcestest.AssetOf("assets/a.bin")
+	if assetPath, exists := rs.Path(asset); !exists || assetPath != "assets/a.bin" {
+		t.Fatalf("got path %q, want assets/a.bin", assetPath)
+	}
+
+	rs.SetVerify(true)
+	got, err := rs.ReadBytes(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q, want %q", got, data)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	files, assets := resourcestest.Files("b/b.bin", "a/a.bin")
+
+	for i, assetPath := range []string{"b/b.bin", "a/a.bin"} {
+		if string(files[assetPath]) != assetPath {
+			t.Fatalf("got contents %q, want %q", files[assetPath], assetPath)
+		}
+		if assets[i] != resourcestest.AssetOf(assetPath) {
+			t.Fatalf("got asset %d for %s, want %d", assets[i], assetPath, resourcestest.AssetOf(assetPath))
+		}
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+}
@@ -0,0 +1,76 @@
+// Package resourcestest provides utilities for testing code that loads assets through a
+// resources.ResourceSystem.
+package resourcestest
+
+import (
+	"hash/fnv"
+	"path"
+	"testing/fstest"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+// AssetOf returns the identifier `flinch-cli generate manifest` assigns to the asset at the given path,
+// which is derived from the file name of the path.
+func AssetOf(assetPath string) resources.Asset {
+	h := fnv.New64a()
+	h.Write([]byte(path.Base(assetPath)))
+	return resources.Asset(h.Sum64())
+}
+
+// Files returns in-memory files at the given slash-separated paths, each containing its own path, along
+// with their assets in the order of the paths.
+func Files(paths ...string) (map[string][]byte, []resources.Asset) {
+	files := make(map[string][]byte, len(paths))
+	assets := make([]resources.Asset, len(paths))
+	for i, assetPath := range paths {
+		files[assetPath] = []byte(assetPath)
+		assets[i] = AssetOf(assetPath)
+	}
+	return files, assets
+}
+
+// NewManifest builds the manifest and digests of an in-memory set of files, keyed by slash-separated path.
+func NewManifest(files map[string][]byte) *resources.Manifest {
+	manifest := &resources.Manifest{
+		Assets:  make(resources.AssetManifest, len(files)),
+		Digests: make(resources.AssetDigests, len(files)),
+	}
+
+	for assetPath, data := range files {
+		asset := AssetOf(assetPath)
+		if existing, exists := manifest.Assets[asset]; exists {
+			panic("resourcestest: " + existing + " and " + assetPath + " share an asset identifier")
+		}
+
+		manifest.Assets[asset] = assetPath
+		manifest.Digests[asset] = resources.NewAssetDigest(data)
+	}
+
+	return manifest
+}
+
+// NewFS returns an in-memory filesystem containing the given files, keyed by slash-separated path.
+func NewFS(files map[string][]byte) fstest.MapFS {
+	fsys := make(fstest.MapFS, len(files))
+	for assetPath, data := range files {
+		fsys[assetPath] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}
+
+// NewSystem creates a ResourceSystem serving the given in-memory files, keyed by slash-separated path.
+//
+// Asset identifiers are assigned with AssetOf, and the digest of every file is recorded so that
+// verification can be enabled with SetVerify. The files are served from a single base layer, which may be
+// replaced with a FaultFS wrapping it to inject failures.
+func NewSystem(name string, files map[string][]byte) *resources.ResourceSystem {
+	manifest := NewManifest(files)
+
+	rs := resources.NewResourceSystem(name, manifest.Assets, resources.ResourceSystemOptions{
+		Digests: manifest.Digests,
+	})
+	rs.SetFileSystem(NewFS(files))
+
+	return rs
+}
@@ -1,7 +1,7 @@
 package resources
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"io/fs"
 	"sync"
@@ -18,6 +18,11 @@ var (
 	}
 )
 
+// NewBatchID returns a new unique batch ID for use with LockAsset outside of a LoadingOperation.
+func NewBatchID() uint64 {
+	return batchID.Add(1)
+}
+
 // ============================== Assets ==============================
 
 // Asset is a unique identifier for a resource within an AssetManifest.
@@ -39,7 +44,8 @@ type AssetLock struct {
 	asset   Asset  // The asset being locked
 
 	rs      *ResourceSystem // The ResourceSystem managing this lock
-	assetMu *sync.Mutex     // Mutex for the specific asset
+	assetMu assetMutex      // Mutex for the specific asset
+	meter   *assetMeter     // Metrics recorded while the lock is held, if a sink is set
 }
 
 // Release unlocks the AssetLock, allowing other threads to acquire the resource.
@@ -66,6 +72,21 @@ type ResourceSystemOptions struct {
 	// Some filesystems may require the root directory to be trimmed from resource paths
 	// to correctly locate resources.
 	TrimRoot bool
+
+	// Digests records the expected size and content hash of each asset file.
+	//
+	// Digests are only checked once verification is enabled with SetVerify. Variants are checked against
+	// the digests recorded for their own identifiers.
+	Digests AssetDigests
+
+	// Variants lists the files that provide assets with localized or resolution-specific versions.
+	//
+	// The variant used for each asset is selected with SetLocale and SetScale.
+	Variants AssetVariants
+
+	// Strict makes NewResourceSystem panic if the manifest fails validation, and enables strict mode as
+	// described by SetStrict. Files are not checked at construction, as no filesystem is set yet.
+	Strict bool
 }
 
 // ResourceSystem represents a collection of resources, providing utilities for loading and managing them.
@@ -75,37 +96,98 @@ type ResourceSystemOptions struct {
 // access to different assets.
 //
 // Note: The ResourceSystem does not manage the lifecycle of the loaded assets themselves. It is the caller's
-// responsibility to handle asset caching, unloading, and memory management as needed.
+// responsibility to handle asset caching, unloading, and memory management as needed. A HandleTable can be
+// used to reference-count loaded values and release them once they are no longer held.
 type ResourceSystem struct {
-	options    ResourceSystemOptions
-	manifest   AssetManifest
-	filesystem fs.FS
-	name       string
-
-	locks   map[uint64]*AssetLock
-	assetMu map[Asset]*sync.Mutex
-	mu      sync.RWMutex
+	options  ResourceSystemOptions
+	manifest AssetManifest
+	paths    map[string]Asset
+	layers   []Layer
+	served   map[Asset]string
+	name     string
+	revision uint64 // Incremented whenever the manifest or the layers change
+
+	locks     map[uint64]*AssetLock
+	assetMu   map[Asset]assetMutex
+	observers map[uint64]func(AssetEvent)
+
+	verify atomic.Bool
+	strict atomic.Bool
+	deps   map[Asset][]Asset
+	scans  map[Asset]uint64 // Incremented whenever an asset changes, so that stale dependency scans are discarded
+
+	locale   string
+	scale    float64
+	selected map[Asset]Variant
+
+	reloaders   []ReloadFunc
+	subscribers []func(ReloadEvent)
+
+	sink       MetricsSink
+	meters     map[Asset]*assetMeter
+	collectors map[uint64]*batchCollector
+
+	mu sync.RWMutex
 }
 
 // NewResourceSystem creates a new ResourceSystem with the given name, manifest, and options.
 func NewResourceSystem(name string, manifest AssetManifest, options ResourceSystemOptions) *ResourceSystem {
-	return &ResourceSystem{
-		locks:    make(map[uint64]*AssetLock),
-		assetMu:  make(map[Asset]*sync.Mutex),
-		name:     name,
-		manifest: manifest,
-		options:  options,
+	paths := make(map[string]Asset, len(manifest))
+	for asset, path := range manifest {
+		paths[path] = asset
+		if options.TrimRoot {
+			paths[trimAssetPathRoot(path)] = asset
+		}
 	}
+	for asset, variants := range options.Variants {
+		for _, variant := range variants {
+			paths[variant.Path] = asset
+			if options.TrimRoot {
+				paths[trimAssetPathRoot(variant.Path)] = asset
+			}
+		}
+	}
+
+	rs := &ResourceSystem{
+		locks:     make(map[uint64]*AssetLock),
+		assetMu:   make(map[Asset]assetMutex),
+		observers: make(map[uint64]func(AssetEvent)),
+		served:    make(map[Asset]string),
+		deps:      make(map[Asset][]Asset),
+		scans:     make(map[Asset]uint64),
+		name:      name,
+		manifest:  manifest,
+		paths:     paths,
+		options:   options,
+		scale:     1,
+		selected:  make(map[Asset]Variant),
+	}
+	rs.selectVariants()
+	rs.strict.Store(options.Strict)
+
+	if options.Strict {
+		if err := rs.Validate().Err(); err != nil {
+			panic(err)
+		}
+	}
+	registerSystem(rs)
+
+	return rs
+}
+
+// Name returns the name of the ResourceSystem.
+func (rs *ResourceSystem) Name() string {
+	return rs.name
 }
 
 // SetFileSystem sets the filesystem to be used by the ResourceSystem for loading assets.
 //
 // The filesystem must implement the fs.FS interface. If no filesystem is set, attempts to
 // read assets will result in an error.
+//
+// SetFileSystem replaces every layer of the ResourceSystem with a single layer named BaseLayer.
 func (rs *ResourceSystem) SetFileSystem(fs fs.FS) {
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
-	rs.filesystem = fs
+	rs.SetLayers(Layer{Name: BaseLayer, FS: fs})
 }
 
 // LockAsset attempts to acquire a lock for the specified asset within the resource system.
@@ -114,65 +196,118 @@ func (rs *ResourceSystem) SetFileSystem(fs fs.FS) {
 //
 // The batchID should be unique per loading operation (typically a goroutine ID or operation ID).
 // A batch can only hold one lock at a time - attempting to acquire multiple locks simultaneously
-// will panic.
+// will panic. Use LockAssets to hold the locks of several assets at once.
 //
 // The returned AssetLock must be released by calling Release() exactly once when done.
 func (rs *ResourceSystem) LockAsset(batchID uint64, asset Asset) *AssetLock {
-	rs.mu.Lock()
-	if _, exists := rs.manifest[asset]; !exists {
-		rs.mu.Unlock()
-		panic(fmt.Sprintf("asset 0x%x does not exist in resource system %s", asset, rs.name))
+	lock, err := rs.LockAssetContext(context.Background(), batchID, asset)
+	if err != nil {
+		panic(err.Error())
 	}
+	return lock
+}
 
-	if _, exists := rs.locks[batchID]; exists {
-		rs.mu.Unlock()
-		panic(fmt.Sprintf("resource batch %d attempted to acquire multiple locks simultaneously", batchID))
+// LockAssetContext behaves like LockAsset, but returns an error instead of panicking, and stops waiting
+// for the asset when the context is cancelled.
+//
+// Unknown assets are reported as an *AssetError wrapping ErrUnknownAsset, and batches that already hold a
+// lock as an *AssetError wrapping ErrBatchHoldsLock. If the context is cancelled before the lock is acquired,
+// no lock is held and ctx.Err() is returned.
+func (rs *ResourceSystem) LockAssetContext(ctx context.Context, batchID uint64, asset Asset) (*AssetLock, error) {
+	locks, err := rs.lockAssets(batchID, []Asset{asset}, func(m assetMutex) error {
+		return m.Lock(ctx)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return locks[0], nil
+}
+
+// Open opens the specified asset for streaming.
+//
+// The returned AssetFile supports reading and seeking, allowing decoders to stream large assets such as
+// audio without reading them into memory first. As with ReadBytes, callers loading an asset should hold
+// its AssetLock while the file is open. The file must be closed when no longer needed.
+func (rs *ResourceSystem) Open(asset Asset) (AssetFile, error) {
+	return rs.OpenContext(context.Background(), asset)
+}
+
+// OpenContext behaves like Open, but reads from the returned file fail once the context is cancelled.
+func (rs *ResourceSystem) OpenContext(ctx context.Context, asset Asset) (AssetFile, error) {
+	return rs.openAsset(ctx, asset, false)
+}
 
-	assetMutex, exists := rs.assetMu[asset]
-	if !exists {
-		assetMutex = &sync.Mutex{}
-		rs.assetMu[asset] = assetMutex
+// openAsset opens an asset for reading. When the asset is verified, files that the caller reads sequentially
+// to the end are verified as they are read, and other files are verified before they are returned.
+func (rs *ResourceSystem) openAsset(ctx context.Context, asset Asset, sequential bool) (AssetFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	lock := assetLocks.Get().(*AssetLock)
-	lock.batchID = batchID
-	lock.asset = asset
-	lock.assetMu = assetMutex
-	lock.rs = rs
+	layer, ref, err := rs.resolve(asset)
+	if err != nil {
+		return nil, err
+	}
 
-	rs.locks[batchID] = lock
-	rs.mu.Unlock()
+	file, err := layer.open(ref)
+	if err != nil {
+		return nil, rs.assetError("open", asset, err)
+	}
 
-	assetMutex.Lock()
+	rs.assetServed(asset, layer)
 
-	return lock
+	assetFile, err := newAssetFile(ctx, file)
+	if err != nil {
+		file.Close()
+		return nil, rs.assetError("read", asset, err)
+	}
+
+	// Digests describe the files of the base layer, so files overridden by patches and mods are not verified.
+	if expected, exists := rs.expectedDigest(ref.id); exists && !ref.overridden {
+		corrupt := &ErrAssetCorrupt{
+			System:   rs.name,
+			Asset:    asset,
+			Path:     ref.path,
+			Expected: expected,
+		}
+
+		if sequential {
+			assetFile = newVerifyingFile(assetFile, corrupt)
+		} else if err := verifyAssetFile(assetFile, corrupt); err != nil {
+			assetFile.Close()
+			return nil, err
+		}
+	}
+
+	if meter := rs.meterFor(asset); meter != nil {
+		assetFile = &meteredFile{AssetFile: assetFile, meter: meter}
+	}
+
+	return assetFile, nil
 }
 
 // ReadBytes reads the raw byte data of the specified asset from the resource system.
 //
 // If the asset does not exist, an error is returned.
 func (rs *ResourceSystem) ReadBytes(asset Asset) ([]byte, error) {
-	rs.mu.RLock()
-	path := rs.manifest[asset]
-	fs := rs.filesystem
-	rs.mu.RUnlock()
-
-	if fs == nil {
-		return nil, fmt.Errorf("resource system %s has no associated filesystem", rs.name)
-	}
-
-	if rs.options.TrimRoot {
-		path = trimAssetPathRoot(path)
-	}
+	return rs.ReadBytesContext(context.Background(), asset)
+}
 
-	file, err := fs.Open(path)
+// ReadBytesContext behaves like ReadBytes, but aborts the read when the context is cancelled.
+//
+// If the context is cancelled, ctx.Err() is returned.
+func (rs *ResourceSystem) ReadBytesContext(ctx context.Context, asset Asset) ([]byte, error) {
+	file, err := rs.openAsset(ctx, asset, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open asset 0x%x (%s) in resource system %s: %w", asset, path, rs.name, err)
+		return nil, err
 	}
 	defer file.Close()
 
-	return io.ReadAll(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // CreateBatch creates a new LoadingOperation batch with the specified loading tasks.
@@ -192,9 +327,50 @@ func (rs *ResourceSystem) CreateBatch(tasks ...LoadingTask) *LoadingOperation {
 // Asset mutexes are intentionally kept in the assetMu map to prevent race conditions
 // where threads may hold references to mutexes after unlocking rs.mu.
 func (rs *ResourceSystem) lockReleased(lock *AssetLock) {
+	rs.mu.Lock()
+	event := AssetEvent{Asset: lock.asset, BatchID: lock.batchID}
+	observer := rs.observers[lock.batchID]
+	report := rs.meterReleased(lock)
+
+	if rs.locks[lock.batchID] == lock {
+		delete(rs.locks, lock.batchID)
+	}
+	if lockDebug.enabled.Load() {
+		lockDebug.released(rs, lock.batchID, lock.asset)
+	}
+	assetLocks.Put(lock)
+	rs.mu.Unlock()
+
+	report()
+
+	if observer != nil {
+		observer(event)
+	}
+}
+
+// lockAbandoned is an internal method called when a batch stops waiting for an asset before acquiring it.
+func (rs *ResourceSystem) lockAbandoned(lock *AssetLock) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	delete(rs.locks, lock.batchID)
+	lock.assetMu = nil
+
+	if rs.locks[lock.batchID] == lock {
+		delete(rs.locks, lock.batchID)
+	}
 	assetLocks.Put(lock)
 }
+
+// observe registers an observer that is notified whenever the given batch releases an asset lock.
+func (rs *ResourceSystem) observe(batchID uint64, observer func(AssetEvent)) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.observers[batchID] = observer
+}
+
+// unobserve removes the observer registered for the given batch.
+func (rs *ResourceSystem) unobserve(batchID uint64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	delete(rs.observers, batchID)
+}
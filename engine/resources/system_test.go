@@ -0,0 +1,119 @@
+package resources_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+func TestLockAssetContextCancel(t *testing.T) {
+	files, assets := newFiles(2)
+	rs := resourcestest.NewSystem("cancel", files)
+
+	holder := resources.NewBatchID()
+	held := rs.LockAsset(holder, assets[0])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := resources.NewBatchID()
+	result := make(chan error, 1)
+	go func() {
+		lock, err := rs.LockAssetContext(ctx, waiter, assets[0])
+		if lock != nil {
+			lock.Release()
+		}
+		result <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+
+	// The cancelled batch released nothing: the holder still owns the asset.
+	if _, err := rs.TryLockAssets(resources.NewBatchID(), assets[0]); !errors.Is(err, resources.ErrLockBusy) {
+		t.Fatalf("got error %v, want ErrLockBusy while the holder owns the asset", err)
+	}
+
+	// The cancelled batch holds no lock, so it may lock another asset.
+	other, err := rs.LockAssetContext(context.Background(), waiter, assets[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Release()
+
+	held.Release()
+
+	lock, err := rs.TryLockAssets(resources.NewBatchID(), assets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Release()
+}
+
+func TestLoadingHandleCancelWhileWaiting(t *testing.T) {
+	files, assets := newFiles(1)
+	rs := resourcestest.NewSystem("cancel", files)
+
+	held := rs.LockAsset(resources.NewBatchID(), assets[0])
+	defer held.Release()
+
+	handle := rs.CreateBatch(func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
+		lock, err := rs.LockAssetContext(ctx, batchID, assets[0])
+		if err != nil {
+			return err
+		}
+		lock.Release()
+		return nil
+	}).Start(newContext())
+
+	time.Sleep(10 * time.Millisecond)
+	handle.Cancel()
+	<-handle.Done()
+
+	if err := handle.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if events := handle.Events(); len(events) != 0 {
+		t.Errorf("got events %v for an asset that was never acquired", events)
+	}
+}
+
+func TestReadBytesContextCancelled(t *testing.T) {
+	files, assets := newFiles(1)
+	rs := resourcestest.NewSystem("cancel", files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	data, err := rs.ReadBytesContext(ctx, assets[0])
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if data != nil {
+		t.Errorf("got %d bytes from a cancelled read", len(data))
+	}
+}
+
+func TestReadBytesFailureReturnsNoData(t *testing.T) {
+	files, assets := newFiles(1)
+	rs := resourcestest.NewSystem("read", files)
+
+	faults := resourcestest.NewFaultFS(resourcestest.NewFS(files), 1)
+	faults.Inject(resourcestest.FailRead("", 4, nil))
+	rs.SetFileSystem(faults)
+
+	data, err := rs.ReadBytes(assets[0])
+	if !errors.Is(err, resourcestest.ErrInjected) {
+		t.Fatalf("got error %v, want the injected read error", err)
+	}
+	if data != nil {
+		t.Errorf("got %q from a failed read, want nil", data)
+	}
+}
@@ -0,0 +1,395 @@
+package resources
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"weak"
+)
+
+// ============================== Validation ==============================
+
+// ErrInvalidPath is returned when an asset path is not a valid path within the root of a filesystem.
+var ErrInvalidPath = errors.New("asset path is outside of the filesystem root")
+
+// IssueKind identifies a problem found while validating a ResourceSystem.
+type IssueKind uint8
+
+const (
+	IssueDuplicatePath IssueKind = iota + 1 // Several assets or variants of the system share a path
+	IssueDuplicateID                        // A variant shares its identifier with a file of another asset
+	IssueSharedID                           // The identifier is also registered by another resource system
+	IssueSharedPath                         // The path is also registered by another resource system
+	IssueOutsideRoot                        // The path is not a valid path within the filesystem root
+	IssueMissingFile                        // No layer of the system has a file for the path
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case IssueDuplicatePath:
+		return "duplicate path"
+	case IssueDuplicateID:
+		return "duplicate identifier"
+	case IssueSharedID:
+		return "shared identifier"
+	case IssueSharedPath:
+		return "shared path"
+	case IssueOutsideRoot:
+		return "outside root"
+	case IssueMissingFile:
+		return "missing file"
+	default:
+		return fmt.Sprintf("IssueKind(%d)", uint8(k))
+	}
+}
+
+// err returns the sentinel error matched by issues of the kind.
+func (k IssueKind) err() error {
+	switch k {
+	case IssueOutsideRoot:
+		return ErrInvalidPath
+	case IssueMissingFile:
+		return fs.ErrNotExist
+	default:
+		return ErrAssetConflict
+	}
+}
+
+// ValidationIssue describes a problem with an asset of a ResourceSystem.
+type ValidationIssue struct {
+	Kind   IssueKind
+	Asset  Asset  // The asset the problem was found for
+	Path   string // Path of the asset or variant file the problem was found for
+	Detail string // Description of the problem, naming the other asset, path or system involved
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: asset 0x%x (%s): %s", i.Kind, i.Asset, i.Path, i.Detail)
+}
+
+// ValidationReport lists the problems found by Validate.
+type ValidationReport struct {
+	System   string
+	Assets   int // Number of assets checked
+	Variants int // Number of variant files checked
+	Issues   []ValidationIssue
+}
+
+// OK reports whether no problem was found.
+func (r *ValidationReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns nil if no problem was found, or an error joining an *AssetError for each issue otherwise.
+//
+// Each AssetError wraps ErrAssetConflict for duplicate and shared identifiers and paths, ErrInvalidPath for
+// paths outside the filesystem root, and fs.ErrNotExist for missing files.
+func (r *ValidationReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+
+	errs := make([]error, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		errs = append(errs, &AssetError{
+			Op:     "validate",
+			System: r.System,
+			Asset:  issue.Asset,
+			Path:   issue.Path,
+			Err:    fmt.Errorf("%w: %s", issue.Kind.err(), issue.Detail),
+		})
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ValidationReport) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "resource system %s: %d assets, %d variants, %d issues", r.System, r.Assets, r.Variants, len(r.Issues))
+	for _, issue := range r.Issues {
+		sb.WriteString("\n\t")
+		sb.WriteString(issue.String())
+	}
+	return sb.String()
+}
+
+// Validate checks the manifest of the ResourceSystem and returns a report of the problems found.
+//
+// Validate reports paths shared by several assets, variant identifiers clashing with other assets, paths
+// that are not valid within the root of a filesystem, and identifiers or paths also registered by another
+// ResourceSystem. If the ResourceSystem has a filesystem, it also reports the asset and variant files that
+// no layer provides.
+func (rs *ResourceSystem) Validate() *ValidationReport {
+	rs.mu.RLock()
+	name := rs.name
+	manifest := rs.manifest
+	variants := rs.options.Variants
+	layers := rs.layers
+	rs.mu.RUnlock()
+
+	report := &ValidationReport{
+		System: name,
+		Assets: len(manifest),
+		Issues: make([]ValidationIssue, 0),
+	}
+	for _, list := range variants {
+		report.Variants += len(list)
+	}
+
+	files := rs.manifestFiles(manifest, variants)
+	report.Issues = append(report.Issues, rs.checkPaths(files)...)
+	report.Issues = append(report.Issues, rs.checkDuplicates(files)...)
+	report.Issues = append(report.Issues, rs.checkSystems(files)...)
+	report.Issues = append(report.Issues, rs.checkFiles(files, layers)...)
+
+	slices.SortStableFunc(report.Issues, func(a, b ValidationIssue) int {
+		return cmp.Or(cmp.Compare(a.Asset, b.Asset), cmp.Compare(a.Kind, b.Kind))
+	})
+
+	return report
+}
+
+// SetStrict enables or disables strict mode.
+//
+// In strict mode, Merge validates the manifest it is given as described by Validate, checking its files
+// against the current layers, and merges nothing if a problem is found. Strict mode may also be enabled
+// at construction with ResourceSystemOptions.Strict.
+func (rs *ResourceSystem) SetStrict(enabled bool) {
+	rs.strict.Store(enabled)
+}
+
+// checkMerge validates a manifest about to be merged in strict mode. Conflicts with the assets of the
+// ResourceSystem itself are reported by Merge.
+func (rs *ResourceSystem) checkMerge(manifest *Manifest) error {
+	report := &ValidationReport{
+		System: rs.name,
+		Assets: len(manifest.Assets),
+		Issues: make([]ValidationIssue, 0),
+	}
+
+	files := rs.manifestFiles(manifest.Assets, nil)
+	report.Issues = append(report.Issues, rs.checkPaths(files)...)
+	report.Issues = append(report.Issues, rs.checkSystems(files)...)
+	report.Issues = append(report.Issues, rs.checkFiles(files, rs.Layers())...)
+
+	return report.Err()
+}
+
+// manifestFile is an asset or variant file checked by Validate.
+type manifestFile struct {
+	asset   Asset // The logical asset the file provides
+	ref     fileRef
+	virtual bool // Whether the file is the logical path of an asset with variants, which need not exist
+}
+
+// manifestFiles lists the files of a manifest and its variants, in a stable order.
+func (rs *ResourceSystem) manifestFiles(manifest AssetManifest, variants AssetVariants) []manifestFile {
+	files := make([]manifestFile, 0, len(manifest))
+	for _, asset := range slices.Sorted(maps.Keys(manifest)) {
+		logical := fileRef{id: asset, path: manifest[asset]}
+		files = append(files, manifestFile{asset: asset, ref: logical, virtual: len(variants[asset]) > 0})
+
+		for _, variant := range variants[asset] {
+			ref := fileRef{id: variant.ID, path: variant.Path}
+			if ref == logical {
+				// The standard variant of the asset is the logical file itself.
+				files[len(files)-1].virtual = false
+				continue
+			}
+			files = append(files, manifestFile{asset: asset, ref: ref})
+		}
+	}
+	return files
+}
+
+// checkPaths reports the paths that are not valid within the root of a filesystem.
+func (rs *ResourceSystem) checkPaths(files []manifestFile) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	for _, file := range files {
+		path := file.ref.path
+		if rs.options.TrimRoot {
+			path = trimAssetPathRoot(path)
+		}
+
+		if !fs.ValidPath(path) || path == "." {
+			issues = append(issues, ValidationIssue{
+				Kind:   IssueOutsideRoot,
+				Asset:  file.asset,
+				Path:   file.ref.path,
+				Detail: fmt.Sprintf("%q is not a valid path within the filesystem root", path),
+			})
+		}
+	}
+	return issues
+}
+
+// checkDuplicates reports the paths and identifiers shared by files of different assets.
+func (rs *ResourceSystem) checkDuplicates(files []manifestFile) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	paths := make(map[string]manifestFile, len(files))
+	ids := make(map[Asset]manifestFile, len(files))
+
+	for _, file := range files {
+		for _, key := range rs.pathKeys(file.ref.path) {
+			if existing, exists := paths[key]; exists && existing.asset != file.asset {
+				issues = append(issues, ValidationIssue{
+					Kind:   IssueDuplicatePath,
+					Asset:  file.asset,
+					Path:   file.ref.path,
+					Detail: fmt.Sprintf("path %s is also used by asset 0x%x (%s)", key, existing.asset, existing.ref.path),
+				})
+				break
+			}
+			paths[key] = file
+		}
+
+		if existing, exists := ids[file.ref.id]; exists && existing.asset != file.asset {
+			issues = append(issues, ValidationIssue{
+				Kind:   IssueDuplicateID,
+				Asset:  file.asset,
+				Path:   file.ref.path,
+				Detail: fmt.Sprintf("identifier 0x%x is also used by %s of asset 0x%x", file.ref.id, existing.ref.path, existing.asset),
+			})
+			continue
+		}
+		ids[file.ref.id] = file
+	}
+
+	return issues
+}
+
+// checkSystems reports the identifiers and paths also registered by other resource systems.
+func (rs *ResourceSystem) checkSystems(files []manifestFile) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+
+	for _, other := range liveSystems() {
+		if other == rs {
+			continue
+		}
+
+		other.mu.RLock()
+		otherName := other.name
+		otherManifest := other.manifest
+		other.mu.RUnlock()
+
+		otherPaths := make(map[string]Asset, len(otherManifest))
+		for asset, path := range otherManifest {
+			otherPaths[path] = asset
+		}
+
+		for _, file := range files {
+			if path, exists := otherManifest[file.asset]; exists && file.ref.id == file.asset {
+				detail := fmt.Sprintf("asset is also registered by resource system %s", otherName)
+				if path != file.ref.path {
+					detail = fmt.Sprintf("identifier collides with %s in resource system %s", path, otherName)
+				}
+				issues = append(issues, ValidationIssue{
+					Kind:   IssueSharedID,
+					Asset:  file.asset,
+					Path:   file.ref.path,
+					Detail: detail,
+				})
+			}
+
+			if asset, exists := otherPaths[file.ref.path]; exists {
+				issues = append(issues, ValidationIssue{
+					Kind:   IssueSharedPath,
+					Asset:  file.asset,
+					Path:   file.ref.path,
+					Detail: fmt.Sprintf("path is also registered by resource system %s for asset 0x%x", otherName, asset),
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// checkFiles reports the files that no layer provides. Nothing is reported if there are no layers.
+func (rs *ResourceSystem) checkFiles(files []manifestFile, layers []Layer) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	if len(layers) == 0 {
+		return issues
+	}
+
+	for _, file := range files {
+		if file.virtual {
+			continue
+		}
+
+		ref := file.ref
+		if rs.options.TrimRoot {
+			ref.path = trimAssetPathRoot(ref.path)
+		}
+		if !fs.ValidPath(ref.path) {
+			continue
+		}
+
+		var errs []error
+		found := false
+		for _, layer := range slices.Backward(layers) {
+			_, err := layer.stat(ref)
+			if err == nil {
+				found = true
+				break
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("layer %s: %w", layer.Name, err))
+			}
+		}
+		if found {
+			continue
+		}
+
+		detail := "no layer provides the file"
+		if len(errs) > 0 {
+			detail = errors.Join(errs...).Error()
+		}
+		issues = append(issues, ValidationIssue{
+			Kind:   IssueMissingFile,
+			Asset:  file.asset,
+			Path:   file.ref.path,
+			Detail: detail,
+		})
+	}
+
+	return issues
+}
+
+// ============================== System Registry ==============================
+
+// systems tracks every ResourceSystem created, so that Validate can detect identifiers and paths shared
+// between them. Systems are referenced weakly, so that registering them does not keep them alive.
+var systems = struct {
+	list []weak.Pointer[ResourceSystem]
+	mu   sync.Mutex
+}{}
+
+// registerSystem adds a newly created ResourceSystem to the registry.
+func registerSystem(rs *ResourceSystem) {
+	systems.mu.Lock()
+	defer systems.mu.Unlock()
+	systems.list = append(systems.list, weak.Make(rs))
+}
+
+// liveSystems returns the registered systems that are still alive, pruning the others from the registry.
+func liveSystems() []*ResourceSystem {
+	systems.mu.Lock()
+	defer systems.mu.Unlock()
+
+	live := make([]*ResourceSystem, 0, len(systems.list))
+	systems.list = slices.DeleteFunc(systems.list, func(p weak.Pointer[ResourceSystem]) bool {
+		rs := p.Value()
+		if rs == nil {
+			return true
+		}
+		live = append(live, rs)
+		return false
+	})
+
+	return live
+}
@@ -0,0 +1,171 @@
+package resources_test
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+)
+
+// Identifiers of the assets of the validated systems. They are shared by the systems of every test, which
+// collect the systems of previous tests first so that they are not reported as sharing them.
+const (
+	validateA resources.Asset = 0x7a11da7e0001 + iota
+	validateB
+	validateC
+)
+
+func TestValidateReportsIssues(t *testing.T) {
+	tests := map[string]struct {
+		manifest resources.AssetManifest
+		variants resources.AssetVariants
+		files    []string
+		others   []resources.AssetManifest // Manifests of other live resource systems
+		want     []resources.IssueKind
+		err      error
+	}{
+		"duplicate path": {
+			manifest: resources.AssetManifest{validateA: "validate/dup.bin", validateB: "validate/dup.bin"},
+			files:    []string{"validate/dup.bin"},
+			want:     []resources.IssueKind{resources.IssueDuplicatePath},
+			err:      resources.ErrAssetConflict,
+		},
+		"variant identifier": {
+			manifest: resources.AssetManifest{validateA: "validate/a.png", validateB: "validate/b.png"},
+			variants: resources.AssetVariants{validateA: {
+				{ID: validateA, Path: "validate/a.png", Scale: 1},
+				{ID: validateB, Path: "validate/a@2x.png", Scale: 2},
+			}},
+			files: []string{"validate/a.png", "validate/a@2x.png", "validate/b.png"},
+			want:  []resources.IssueKind{resources.IssueDuplicateID},
+			err:   resources.ErrAssetConflict,
+		},
+		"shared identifier": {
+			manifest: resources.AssetManifest{validateA: "validate/mine.bin"},
+			files:    []string{"validate/mine.bin"},
+			others:   []resources.AssetManifest{{validateA: "validate/theirs.bin"}},
+			want:     []resources.IssueKind{resources.IssueSharedID},
+			err:      resources.ErrAssetConflict,
+		},
+		"shared path": {
+			manifest: resources.AssetManifest{validateA: "validate/common.bin"},
+			files:    []string{"validate/common.bin"},
+			others:   []resources.AssetManifest{{validateB: "validate/common.bin"}},
+			want:     []resources.IssueKind{resources.IssueSharedPath},
+			err:      resources.ErrAssetConflict,
+		},
+		"outside root": {
+			manifest: resources.AssetManifest{validateA: "../validate/escape.bin"},
+			want:     []resources.IssueKind{resources.IssueOutsideRoot},
+			err:      resources.ErrInvalidPath,
+		},
+		"missing file": {
+			manifest: resources.AssetManifest{validateA: "validate/present.bin", validateB: "validate/absent.bin"},
+			files:    []string{"validate/present.bin"},
+			want:     []resources.IssueKind{resources.IssueMissingFile},
+			err:      fs.ErrNotExist,
+		},
+		"valid": {
+			manifest: resources.AssetManifest{validateA: "validate/a.bin", validateC: "validate/c.bin"},
+			files:    []string{"validate/a.bin", "validate/c.bin"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			runtime.GC()
+
+			others := make([]*resources.ResourceSystem, len(test.others))
+			for i, manifest := range test.others {
+				others[i] = resources.NewResourceSystem("other", manifest, resources.ResourceSystemOptions{})
+			}
+			defer runtime.KeepAlive(others)
+
+			files, _ := resourcestest.Files(test.files...)
+			rs := resources.NewResourceSystem("validate", test.manifest, resources.ResourceSystemOptions{
+				Variants: test.variants,
+			})
+			rs.SetFileSystem(resourcestest.NewFS(files))
+
+			report := rs.Validate()
+
+			kinds := make([]resources.IssueKind, len(report.Issues))
+			for i, issue := range report.Issues {
+				kinds[i] = issue.Kind
+			}
+			if !slices.Equal(kinds, test.want) {
+				t.Fatalf("got issues %v, want %v", report.Issues, test.want)
+			}
+
+			err := report.Err()
+			if test.err == nil {
+				if err != nil || !report.OK() {
+					t.Fatalf("got error %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			var assetErr *resources.AssetError
+			if !errors.As(err, &assetErr) || assetErr.System != "validate" || assetErr.Asset != report.Issues[0].Asset {
+				t.Fatalf("got error %v, want an AssetError for asset 0x%x", err, report.Issues[0].Asset)
+			}
+		})
+	}
+}
+
+func TestStrictSystemPanicsOnInvalidManifest(t *testing.T) {
+	runtime.GC()
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, resources.ErrAssetConflict) {
+			t.Fatalf("got panic %v, want an error wrapping ErrAssetConflict", err)
+		}
+	}()
+
+	resources.NewResourceSystem("strict", resources.AssetManifest{
+		validateA: "validate/strict.bin",
+		validateB: "validate/strict.bin",
+	}, resources.ResourceSystemOptions{Strict: true})
+
+	t.Fatal("strict resource system created from an invalid manifest")
+}
+
+func TestStrictMergeRejectsInvalidManifest(t *testing.T) {
+	runtime.GC()
+
+	files, _ := resourcestest.Files("validate/base.bin")
+	rs := resources.NewResourceSystem("strict", resources.AssetManifest{validateA: "validate/base.bin"}, resources.ResourceSystemOptions{
+		Strict: true,
+	})
+	rs.SetFileSystem(resourcestest.NewFS(files))
+
+	invalid := &resources.Manifest{Assets: resources.AssetManifest{
+		validateB: "validate/merged.bin",
+		validateC: "../validate/escape.bin",
+	}}
+
+	err := rs.Merge(invalid)
+	if !errors.Is(err, resources.ErrInvalidPath) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v, want the path outside the root and the missing file to be reported", err)
+	}
+	if rs.Contains(validateB) || rs.Contains(validateC) {
+		t.Fatal("strict merge merged assets of an invalid manifest")
+	}
+
+	// Without strict mode, the manifest is merged as is.
+	rs.SetStrict(false)
+	if err := rs.Merge(invalid); err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Contains(validateB) || !rs.Contains(validateC) {
+		t.Fatal("assets not merged once strict mode was disabled")
+	}
+}
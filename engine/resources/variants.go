@@ -0,0 +1,154 @@
+package resources
+
+import (
+	"cmp"
+	"strings"
+)
+
+// ============================== Variants ==============================
+
+// Variant is one of the files that provide a logical asset, such as a localized or high-resolution
+// version of an image.
+//
+// `flinch-cli generate manifest` groups files named after the convention name[@<scale>x][.<locale>].ext
+// under the logical asset name.ext, e.g. splash.fr.png, splash@2x.png and splash@2x.fr.png are variants of
+// splash.png. Locale suffixes are only recognised for the locales passed to the generator with --locale.
+type Variant struct {
+	ID     Asset   // Identifier of the variant file, used to look it up in an AssetFS and its digest
+	Path   string  // Path of the variant file, in the same form as manifest paths
+	Locale string  // Locale of the variant, e.g. "fr" or "pt-BR", or empty if it is not localized
+	Scale  float64 // Resolution scale of the variant, 1 for standard resolution
+}
+
+// AssetVariants maps logical Asset identifiers to the files that provide them.
+type AssetVariants map[Asset][]Variant
+
+// SetLocale sets the locale used to select asset variants, e.g. "fr-CA".
+//
+// For each asset with variants, the variants of the locale are preferred, then those of its language
+// ("fr"), then those that are not localized. If none of these exist, the first variant of the asset is
+// used. Within the chosen locale, the variant is selected by scale as described by SetScale.
+//
+// SetLocale returns the assets whose selected variant changed. Values already loaded for them are not
+// reloaded automatically; they may be passed to Reload.
+func (rs *ResourceSystem) SetLocale(locale string) []Asset {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.locale = locale
+	return rs.selectVariants()
+}
+
+// SetScale sets the preferred resolution scale used to select asset variants, e.g. 2 for high-DPI displays.
+//
+// The variant with the smallest scale that is at least the preferred scale is selected, or the variant with
+// the largest scale if all of them are smaller. The default scale is 1.
+//
+// SetScale returns the assets whose selected variant changed, as with SetLocale.
+func (rs *ResourceSystem) SetScale(scale float64) []Asset {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.scale = scale
+	return rs.selectVariants()
+}
+
+// Variant returns the variant currently selected for the asset, if the asset has variants.
+func (rs *ResourceSystem) Variant(asset Asset) (Variant, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	variant, exists := rs.selected[asset]
+	return variant, exists
+}
+
+// Variants returns every variant of the asset.
+func (rs *ResourceSystem) Variants(asset Asset) []Variant {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	return append([]Variant(nil), rs.options.Variants[asset]...)
+}
+
+// selectVariants selects the variant of every asset with variants and returns the assets whose selection
+// changed. It must be called with rs.mu held.
+func (rs *ResourceSystem) selectVariants() []Asset {
+	changed := make([]Asset, 0)
+	chain := localeChain(rs.locale)
+
+	for asset, variants := range rs.options.Variants {
+		variant, exists := selectVariant(variants, chain, rs.scale)
+		if !exists {
+			continue
+		}
+
+		if previous, selected := rs.selected[asset]; !selected || previous != variant {
+			rs.selected[asset] = variant
+			changed = append(changed, asset)
+		}
+	}
+
+	return changed
+}
+
+// localeChain returns the locales to search for a locale, from the most to the least specific,
+// e.g. "fr-CA", "fr" and "".
+func localeChain(locale string) []string {
+	chain := make([]string, 0, 3)
+	if locale == "" {
+		return append(chain, "")
+	}
+
+	chain = append(chain, locale)
+	if language, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		chain = append(chain, language)
+	}
+	return append(chain, "")
+}
+
+// selectVariant selects the best variant for a locale chain and preferred scale.
+func selectVariant(variants []Variant, chain []string, scale float64) (Variant, bool) {
+	if len(variants) == 0 {
+		return Variant{}, false
+	}
+
+	for _, locale := range chain {
+		best, found := Variant{}, false
+		for _, variant := range variants {
+			if !sameLocale(variant.Locale, locale) {
+				continue
+			}
+			if !found || betterScale(variant.Scale, best.Scale, scale) {
+				best, found = variant, true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+
+	return variants[0], true
+}
+
+// sameLocale reports whether two locales are equal, ignoring case and the separator of the region.
+func sameLocale(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "_", "-"), strings.ReplaceAll(b, "_", "-"))
+}
+
+// betterScale reports whether candidate is a better match than current for the preferred scale.
+func betterScale(candidate, current, preferred float64) bool {
+	candidate = cmp.Or(candidate, 1)
+	current = cmp.Or(current, 1)
+	preferred = cmp.Or(preferred, 1)
+
+	switch {
+	case candidate >= preferred && current >= preferred:
+		return candidate < current
+	case candidate >= preferred:
+		return true
+	case current >= preferred:
+		return false
+	default:
+		return candidate > current
+	}
+}
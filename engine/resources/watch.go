@@ -0,0 +1,190 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ============================== Reloading ==============================
+
+// ReloadFunc reloads an asset whose file has changed on disk.
+//
+// Reload functions are called for every changed asset and are expected to ignore assets they have
+// not loaded. They must acquire the asset lock themselves before reading the asset.
+type ReloadFunc func(ctx context.Context, rs *ResourceSystem, asset Asset) error
+
+// ReloadEvent describes an asset that was reloaded after its file changed.
+type ReloadEvent struct {
+	Asset Asset  // The asset that changed
+	Path  string // Path of the asset within the resource system's filesystem
+	Err   error  // Errors returned by reload functions, if any
+}
+
+// AddReloader registers a function that is called whenever a watcher detects that an asset has changed.
+func (rs *ResourceSystem) AddReloader(fn ReloadFunc) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.reloaders = append(rs.reloaders, fn)
+}
+
+// OnReload registers a function that is called after the reload functions have run for a changed asset.
+//
+// The function is called on the watcher goroutine and must not block. Caches such as storage.Cache keep the
+// values they reloaded aside until the game loop applies them, so the function may still observe the
+// previous value of the asset in a cache.
+func (rs *ResourceSystem) OnReload(fn func(ReloadEvent)) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.subscribers = append(rs.subscribers, fn)
+}
+
+// Reload runs every registered reload function for the asset and notifies reload subscribers.
+func (rs *ResourceSystem) Reload(ctx context.Context, asset Asset) error {
+	rs.mu.Lock()
+	reloaders := rs.reloaders
+	subscribers := rs.subscribers
+
+	// The asset may reference different dependencies after it changed. Scans of the previous contents that are
+	// still in progress must not remember their result.
+	delete(rs.deps, asset)
+	rs.scans[asset]++
+	rs.mu.Unlock()
+
+	errs := make([]error, 0)
+	for _, reload := range reloaders {
+		if err := reload(ctx, rs, asset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	_, ref, _ := rs.resolve(asset)
+	event := ReloadEvent{
+		Asset: asset,
+		Path:  ref.path,
+		Err:   errors.Join(errs...),
+	}
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+
+	return event.Err
+}
+
+// Watch starts a Watcher that polls the files of every asset in the manifest for changes.
+//
+// Changed assets are reloaded through the functions registered with AddReloader. An asset is also
+// considered changed when a different layer starts serving it. Watching is intended
+// for development with filesystems backed by a real directory, such as os.DirFS; filesystems that do not
+// report modification times, such as embed.FS, never report changes.
+//
+// The watcher runs until the context is cancelled or Stop is called.
+func (rs *ResourceSystem) Watch(ctx context.Context, interval time.Duration) *Watcher {
+	ctx, cancel := context.WithCancel(ctx)
+
+	w := &Watcher{
+		rs:     rs,
+		stamps: make(map[Asset]fileStamp),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	w.scan()
+
+	go w.run(ctx, interval)
+
+	return w
+}
+
+// Watcher polls the files backing a ResourceSystem and reloads assets when they change.
+type Watcher struct {
+	rs     *ResourceSystem
+	stamps map[Asset]fileStamp
+	mu     sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// fileStamp records the attributes of a file used to detect changes.
+type fileStamp struct {
+	layer   string
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// Stop stops the watcher and waits for any in-progress reload to finish.
+func (w *Watcher) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// Check polls the asset files once and reloads those that changed since the previous check.
+//
+// Check returns the assets that changed. It is called periodically by the watcher, but may also be
+// called directly, for example from a debug key binding.
+func (w *Watcher) Check(ctx context.Context) []Asset {
+	changed := w.scan()
+	for _, asset := range changed {
+		if ctx.Err() != nil {
+			break
+		}
+		w.rs.Reload(ctx, asset)
+	}
+	return changed
+}
+
+func (w *Watcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// scan stats every asset file and returns the assets whose stamps differ from the previous scan.
+func (w *Watcher) scan() []Asset {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rs.mu.RLock()
+	assets := make([]Asset, 0, len(w.rs.manifest))
+	for asset := range w.rs.manifest {
+		assets = append(assets, asset)
+	}
+	w.rs.mu.RUnlock()
+
+	changed := make([]Asset, 0)
+	for _, asset := range assets {
+		stamp := fileStamp{}
+
+		if layer, ref, err := w.rs.resolve(asset); err == nil {
+			if info, err := layer.stat(ref); err == nil {
+				stamp = fileStamp{
+					layer:   layer.Name,
+					modTime: info.ModTime(),
+					size:    info.Size(),
+					exists:  true,
+				}
+			}
+		}
+
+		previous, seen := w.stamps[asset]
+		w.stamps[asset] = stamp
+
+		if seen && stamp.exists && stamp != previous {
+			changed = append(changed, asset)
+		}
+	}
+
+	return changed
+}
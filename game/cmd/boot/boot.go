@@ -2,19 +2,47 @@ package boot
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/adm87/flinch/data"
+	"github.com/adm87/flinch/engine/crypt"
 	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/httpfs"
+	"github.com/adm87/flinch/engine/pack"
+	"github.com/adm87/flinch/engine/resources"
 	"github.com/adm87/flinch/game/src/game"
+	"github.com/adm87/flinch/storage/documents"
+	"github.com/adm87/flinch/storage/images"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/spf13/cobra"
 )
 
+// assetKey is the hex-encoded AES key that assets were encrypted with, if any. It is set at build time with
+// -ldflags "-X github.com/adm87/flinch/game/cmd/boot.assetKey=<key>", and may be overridden with --asset-key.
+var assetKey string
+
+// hotReloadInterval is how often asset files are polled for changes when hot reloading is enabled.
+const hotReloadInterval = 500 * time.Millisecond
+
 func Command() *cobra.Command {
 	var (
-		rootPath string
+		rootPath  string
+		packPath  string
+		assetsURL string
+		layers    []string
+		hotReload bool
+		verify    bool
+		strict    bool
+		lockDebug bool
+		metrics   string
+		locale    string
+		scale     float64
+
+		packReader *pack.Reader // Pack the assets are read from, closed once the game exits
 	)
 
 	command := &cobra.Command{
@@ -26,14 +54,146 @@ func Command() *cobra.Command {
 				return err
 			}
 
-			// Link the asset resource system to its filesystem on disk.
-			data.Assets.SetFileSystem(os.DirFS(filepath.Join(absRoot, "data", "assets")))
+			// Link the asset resource system to its filesystem on disk, to a pack when one is given, or to a
+			// `flinch-cli serve-assets` server on another machine.
+			switch {
+			case packPath != "":
+				reader, err := pack.OpenFile(packPath)
+				if err != nil {
+					return err
+				}
+				packReader = reader
+				data.Assets.SetFileSystem(reader)
+			case assetsURL != "":
+				remote, err := httpfs.New(assetsURL, httpfs.Options{})
+				if err != nil {
+					return err
+				}
+				data.Assets.SetFileSystem(remote)
+			default:
+				data.Assets.SetFileSystem(os.DirFS(filepath.Join(absRoot, "data", "assets")))
+			}
+
+			// Decrypt the base assets when they were encrypted by flinch-cli. Plain base assets, such as the
+			// assets directory of a development checkout, are served as-is even when a key is built in. Layers
+			// given with --layer are not encrypted, so that patches and mods can be authored as plain files.
+			if assetKey != "" {
+				key, err := crypt.ParseKey(assetKey)
+				if err != nil {
+					return err
+				}
+				for _, rs := range []*resources.ResourceSystem{data.Assets, data.Static} {
+					if err := decryptBaseLayer(rs, key); err != nil {
+						return err
+					}
+				}
+			}
+
+			// Tiled maps and tilesets reference their tilesets and images by relative path.
+			resources.RegisterScanner(resources.ScanXMLSources, ".tmx", ".tsx")
+
+			// Check asset contents against the digests recorded in the manifests.
+			data.Assets.SetVerify(verify)
+			data.Static.SetVerify(verify)
+
+			// Select localized and high-resolution asset variants.
+			for _, rs := range []*resources.ResourceSystem{data.Assets, data.Static} {
+				rs.SetLocale(locale)
+				rs.SetScale(scale)
+			}
+
+			// Overlay patch and mod directories on top of the base assets. Layers are named by their absolute
+			// path, as directories in different places often share a name such as "mods".
+			pushed := make(map[string]struct{}, len(layers))
+			for _, layer := range layers {
+				absLayer, err := filepath.Abs(layer)
+				if err != nil {
+					return err
+				}
+				if _, exists := pushed[absLayer]; exists {
+					return fmt.Errorf("asset layer %s given more than once", absLayer)
+				}
+				pushed[absLayer] = struct{}{}
+
+				data.Assets.PushLayer(absLayer, os.DirFS(absLayer))
+			}
+
+			// Fail fast on manifests that clash with each other or reference files that do not exist.
+			if strict {
+				for _, rs := range []*resources.ResourceSystem{data.Assets, data.Static} {
+					rs.SetStrict(true)
+					if err := rs.Validate().Err(); err != nil {
+						return err
+					}
+				}
+			}
 
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx := flinch.NewContext(cmd.Context(), cmd.OutOrStdout())
-			if err := game.Run(ctx); err != nil {
+
+			// Record load metrics, logging batch summaries and exporting a report at shutdown.
+			report := resources.NewMetricsReport(resources.MetricsReportOptions{})
+			if metrics != "" {
+				sink := resources.MultiSink(report, resources.NewLoggerSink(ctx.Logger()))
+				data.Assets.SetMetrics(sink)
+				data.Static.SetMetrics(sink)
+			}
+
+			// Development: report loaders that lock assets of several resource systems in inconsistent orders.
+			if lockDebug {
+				resources.SetLockDebug(true)
+				resources.OnLockInversion(func(inversion resources.LockInversion) {
+					ctx.Logger().Warn("Asset lock-order inversion", "inversion", inversion)
+				})
+			}
+
+			// Development: reload assets from disk as they are edited.
+			var watcher *resources.Watcher
+			if hotReload {
+				data.Assets.OnReload(func(event resources.ReloadEvent) {
+					if event.Err != nil {
+						ctx.Logger().Error("Asset reload failed", "path", event.Path, "error", event.Err)
+						return
+					}
+					ctx.Logger().Info("Asset reloaded", "path", event.Path)
+				})
+
+				watcher = data.Assets.Watch(ctx, hotReloadInterval)
+			}
+
+			err := game.Run(ctx)
+
+			// The watcher polls the pack, so it is stopped before the pack is closed. Neither can be deferred, as
+			// the command exits the process below.
+			if watcher != nil {
+				watcher.Stop()
+			}
+			if packReader != nil {
+				if err := packReader.Close(); err != nil {
+					ctx.Logger().Error("Failed to close asset pack", "error", err)
+				}
+			}
+
+			// Report any images and documents still referenced by a handle at shutdown.
+			for _, leak := range images.Leaks() {
+				ctx.Logger().Warn("Image handle leaked", "leak", leak)
+			}
+			for _, leak := range documents.Leaks() {
+				ctx.Logger().Warn("Document handle leaked", "leak", leak)
+			}
+
+			if metrics != "" {
+				stats := images.Stats()
+				ctx.Logger().Info("Image cache", "hits", stats.Hits, "misses", stats.Misses, "usage", stats.Usage, "evictions", stats.Evictions)
+
+				if err := writeMetrics(metrics, report); err != nil {
+					ctx.Logger().Error("Failed to write metrics report", "error", err)
+				}
+			}
+
+			if err != nil {
 				if errors.Is(err, ebiten.Termination) {
 					ctx.Logger().Info("Game terminated")
 					os.Exit(0)
@@ -45,6 +205,74 @@ func Command() *cobra.Command {
 	}
 
 	command.PersistentFlags().StringVar(&rootPath, "root-path", "", "Path to the root directory")
+	command.PersistentFlags().StringVar(&packPath, "assets-pack", "", "Path to a pack file to load assets from instead of the assets directory")
+	command.PersistentFlags().StringVar(&assetsURL, "assets-url", "", "URL of a flinch-cli serve-assets server to load assets from instead of the assets directory")
+	command.PersistentFlags().StringArrayVar(&layers, "layer", nil, "Asset directories layered over the base assets, lowest priority first")
+	command.PersistentFlags().BoolVar(&hotReload, "hot-reload", false, "Reload assets from disk when they change")
+	command.PersistentFlags().BoolVar(&verify, "verify-assets", false, "Verify asset contents against their manifest digests, except files overridden by --layer")
+	command.PersistentFlags().BoolVar(&strict, "strict-assets", false, "Validate asset manifests at boot and reject invalid merged manifests")
+	command.PersistentFlags().StringVar(&locale, "locale", "", "Locale used to select localized asset variants, e.g. fr-CA")
+	command.PersistentFlags().Float64Var(&scale, "asset-scale", 1, "Preferred resolution scale of asset variants, e.g. 2 for high-DPI displays")
+	command.PersistentFlags().StringVar(&assetKey, "asset-key", assetKey, "Hex-encoded AES key to decrypt encrypted assets with")
+	command.PersistentFlags().BoolVar(&lockDebug, "debug-locks", false, "Report asset locks acquired in inconsistent orders across resource systems")
+	command.PersistentFlags().StringVar(&metrics, "metrics", "", "Path to write a JSON report of asset load metrics to at shutdown")
 
 	return command
 }
+
+// decryptBaseLayer wraps the filesystem of the base layer of a resource system with a decrypting filesystem,
+// if its files are encrypted. It must be called before other layers are pushed.
+func decryptBaseLayer(rs *resources.ResourceSystem, key []byte) error {
+	layers := rs.Layers()
+	if len(layers) == 0 || layers[0].FS == nil {
+		return nil
+	}
+
+	encrypted, err := isEncrypted(rs)
+	if err != nil || !encrypted {
+		return err
+	}
+
+	fsys, err := crypt.NewFS(layers[0].FS, key)
+	if err != nil {
+		return err
+	}
+
+	layers[0].FS = fsys
+	rs.SetLayers(layers...)
+	return nil
+}
+
+// isEncrypted reports whether the files of the base layer of a resource system are encrypted, judging by the
+// first of its assets whose file exists.
+func isEncrypted(rs *resources.ResourceSystem) (bool, error) {
+	for asset := range rs.Assets() {
+		file, err := rs.Open(asset)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		encrypted, err := crypt.IsEncrypted(file)
+		file.Close()
+		return encrypted, err
+	}
+	return false, nil
+}
+
+// writeMetrics writes the load metrics report to the file at path.
+func writeMetrics(path string, report *resources.MetricsReport) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := report.WriteJSON(file); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
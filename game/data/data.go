@@ -46,17 +46,47 @@ var (
 	}
 )
 
+// =============== Digests ===============
+
+var (
+	AssetsDigests = resources.AssetDigests{
+		0xfca5063c33d5d3f6: {Size: 4255, SHA256: "24bed8b8cd7656d42ac521317a33c8f82143f400b19c9c24fe0b4c5a1f994bad"},
+		0x17d0b26b6cf1aecc: {Size: 2029, SHA256: "40233cf93007d705fe95a9f1d453a301d1cea616be7cdd0c664d57c850251d71"},
+		0x6b45fe0a52c037d3: {Size: 19850, SHA256: "a27a7effeac003a3c6014e1db2d4e3527e4942c72716d1296e00bd419a598ed0"},
+		0x7d30d7ac63574117: {Size: 5952, SHA256: "7a1b594d188dc12dca1da8a61549c6a826833501d2c03d9d142df51fa78b44c0"},
+		0x9e04cca827d1ffc6: {Size: 4489, SHA256: "8b6d9e4bf7cfd2939ebd660208352eadead09e44e27816dc014654528b145869"},
+		0x887669217c4d6120: {Size: 1990, SHA256: "22622d54dfc9d85346c9b75b999c8604e6eb336e42c4708bcf8b21094d8a2dad"},
+		0xcb8d155ee372a4af: {Size: 5913, SHA256: "a3bbff36594baa36c803a67e9152a20a80f2faeb3c22ca84ac06e0a70f56553d"},
+		0x7e18b3a57e2d5ee7: {Size: 418, SHA256: "ab0545526582d399d6c44fbe9a952447802019238e027c7ea1a8cd99d1ee028d"},
+		0x5a8c9444088a8942: {Size: 251, SHA256: "914336640e1e9f1f812e27ef7e2a3f2aa60083fe571d59f001c65afb7e428c23"},
+	}
+	StaticDigests = resources.AssetDigests{
+		0x783414191130c7d: {Size: 61182, SHA256: "a8ef0e04eafd5db63f69b7f8447facca436858764580911ac686aee27eca4d96"},
+	}
+)
+
+// =============== Variants ===============
+
+var (
+	AssetsVariants = resources.AssetVariants{}
+	StaticVariants = resources.AssetVariants{}
+)
+
 // =============== Resource Systems ===============
 
 var (
 	Assets = resources.NewResourceSystem("assets", AssetsManifest,
 		resources.ResourceSystemOptions{
 			TrimRoot: true,
+			Digests:  AssetsDigests,
+			Variants: AssetsVariants,
 		},
 	)
 	Static = resources.NewResourceSystem("static", StaticManifest,
 		resources.ResourceSystemOptions{
 			TrimRoot: false,
+			Digests:  StaticDigests,
+			Variants: StaticVariants,
 		},
 	)
 )
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"image/color"
 
 	"github.com/adm87/flinch/engine/flinch"
@@ -8,6 +9,8 @@ import (
 	"github.com/adm87/flinch/game/src/game/states/gameplay"
 	"github.com/adm87/flinch/game/src/game/states/splashscreen"
 	"github.com/adm87/flinch/game/src/state"
+	"github.com/adm87/flinch/storage/documents"
+	"github.com/adm87/flinch/storage/images"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
@@ -57,12 +60,15 @@ func Run(ctx *flinch.Context) error {
 	fsm.SetNext(bootStateID)
 	fsm.SetTransitions(transitions)
 
-	return ebiten.RunGame(&ggame{
+	err := ebiten.RunGame(&ggame{
 		ctx: ctx,
 		op: &ebiten.DrawImageOptions{
 			Filter: ebiten.FilterLinear,
 		},
 	})
+
+	// Exit the last state and release the assets of every state, so that only leaked handles remain.
+	return errors.Join(err, fsm.Shutdown(ctx))
 }
 
 func (g *ggame) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -83,6 +89,10 @@ func (g *ggame) Update() error {
 	// Update the game context.
 	g.ctx.Update()
 
+	// Swap in assets reloaded by the hot reload watcher, between frames so none is replaced while drawn.
+	images.ApplyReloads()
+	documents.ApplyReloads()
+
 	// Process the FSM.
 	return fsm.Process(g.ctx)
 }
@@ -5,7 +5,9 @@ import (
 
 	"github.com/adm87/flinch/data"
 	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
 	"github.com/adm87/flinch/game/src/state"
+	"github.com/adm87/flinch/storage"
 	"github.com/adm87/flinch/storage/images"
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -17,6 +19,9 @@ const (
 var ()
 
 type State struct {
+	scope   *storage.Scope
+	loading *resources.LoadingHandle
+	splash  *resources.Handle[*ebiten.Image]
 	img     *ebiten.Image
 	op      *ebiten.DrawImageOptions
 	opacity float64
@@ -24,6 +29,7 @@ type State struct {
 
 func New() state.State[flinch.Context] {
 	return &State{
+		scope: storage.NewScope("splashscreen"),
 		op: &ebiten.DrawImageOptions{
 			Filter: ebiten.FilterLinear,
 		},
@@ -31,35 +37,50 @@ func New() state.State[flinch.Context] {
 	}
 }
 
+// Scope returns the scope of the assets loaded by the state, which the FSM releases after the state exits.
+func (s *State) Scope() state.Scope {
+	return s.scope
+}
+
 func (s *State) Enter(ctx *flinch.Context) error {
+	// The splash image must stay resident for the whole state, regardless of the image budget.
+	images.Pin(data.Static, data.Splash1920x1080Black)
+	s.scope.Defer(func() {
+		images.Unpin(data.Static, data.Splash1920x1080Black)
+	})
+
+	// Assets loaded through the scope are released with it once the state has been exited.
 	loadingOp := data.Static.CreateBatch(
-		images.NewLoader(data.Splash1920x1080Black),
+		resources.NewLoader(data.Splash1920x1080Black),
 	)
-	if err := loadingOp.Execute(ctx); err != nil {
-		return err
-	}
-
-	img, ok := images.Get(data.Splash1920x1080Black)
-	if !ok {
-		return errors.New("failed to load splashscreen")
-	}
-	s.img = img
+	s.loading = loadingOp.Start(s.scope.Context(ctx))
 
 	return nil
 }
 
 func (s *State) Exit(ctx *flinch.Context) error {
-	images.Delete(data.Splash1920x1080Black)
+	// Abort any in-flight load and wait for it so the splash image cannot be cached after the scope is released.
+	s.loading.Cancel()
+	<-s.loading.Done()
+
+	s.splash = nil
 	s.img = nil
 
 	return nil
 }
 
 func (s *State) Process(ctx *flinch.Context) (state.StateExitCondition, error) {
+	if s.img == nil {
+		return state.NilExitCondition, s.awaitSplash()
+	}
 	return state.NilExitCondition, nil
 }
 
 func (s *State) Draw(ctx *flinch.Context) {
+	if s.img == nil {
+		return
+	}
+
 	w, h := ctx.Screen().Size()
 	sx, sy, sw, sh := transformScreen(
 		s.img.Bounds().Dx(),
@@ -75,6 +96,29 @@ func (s *State) Draw(ctx *flinch.Context) {
 	ctx.Screen().Buffer().DrawImage(s.img, s.op)
 }
 
+// awaitSplash checks whether the splash image has finished loading without blocking the frame.
+func (s *State) awaitSplash() error {
+	select {
+	case <-s.loading.Done():
+	default:
+		return nil
+	}
+
+	if err := s.loading.Err(); err != nil {
+		return err
+	}
+
+	splash, ok := images.Acquire(data.Static, data.Splash1920x1080Black)
+	if !ok {
+		return errors.New("failed to load splashscreen")
+	}
+	s.splash = splash
+	s.scope.Defer(splash.Release)
+	s.img = splash.Value()
+
+	return nil
+}
+
 func transformScreen(sw, sh, tw, th int) (float64, float64, float64, float64) {
 	scale := float64(th) / float64(sh)
 	x := (float64(tw) - float64(sw)*scale) / 2
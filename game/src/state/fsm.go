@@ -30,6 +30,20 @@ type State[Context any] interface {
 	Process(ctx *Context) (StateExitCondition, error)
 }
 
+// Scope releases the resources acquired by a state, such as a storage.Scope.
+type Scope interface {
+	Release()
+}
+
+// Scoped is implemented by states that acquire their resources through a Scope.
+//
+// The FSM releases the scope of a state once the state has been exited and the next state has been entered
+// successfully, so that resources shared by both states are not unloaded and loaded again. The scope of a
+// state that fails to enter is released right away, and the scope of the last state is released by Shutdown.
+type Scoped interface {
+	Scope() Scope
+}
+
 type StateFactory[Context any] func() State[Context]
 
 type States[Context any] map[StateID]StateFactory[Context]
@@ -43,6 +57,7 @@ type FSM[Context any] struct {
 
 	states States[Context]
 	state  State[Context]
+	exited []Scope // Scopes of exited states, released once the next state has been entered
 
 	isTransitioning bool
 }
@@ -92,6 +107,27 @@ func (fsm *FSM[Context]) Process(ctx *Context) error {
 	return nil
 }
 
+// Shutdown exits the current state and releases the scopes of every state, leaving the FSM without a state.
+// It should be called once the game loop has stopped.
+func (fsm *FSM[Context]) Shutdown(ctx *Context) error {
+	var err error
+	if fsm.state != nil {
+		if exitErr := fsm.state.Exit(ctx); exitErr != nil {
+			err = NewError(fsm.current, NilExitCondition, NilStateID, exitErr.Error())
+		}
+		if scoped, ok := fsm.state.(Scoped); ok {
+			fsm.exited = append(fsm.exited, scoped.Scope())
+		}
+	}
+
+	fsm.state = nil
+	fsm.current = NilStateID
+	fsm.next = NilStateID
+	releaseScopes(fsm)
+
+	return err
+}
+
 func (fsm *FSM[Context]) AddState(stateID StateID, factory StateFactory[Context]) *FSM[Context] {
 	fsm.states[stateID] = factory
 	return fsm
@@ -114,6 +150,11 @@ func changeState[Context any](fsm *FSM[Context], ctx *Context) error {
 		if err := fsm.state.Exit(ctx); err != nil {
 			return NewError(fsm.current, NilExitCondition, fsm.next, err.Error())
 		}
+
+		if scoped, ok := fsm.state.(Scoped); ok {
+			fsm.exited = append(fsm.exited, scoped.Scope())
+		}
+		fsm.state = nil
 	}
 
 	factory, exists := fsm.states[fsm.next]
@@ -121,14 +162,21 @@ func changeState[Context any](fsm *FSM[Context], ctx *Context) error {
 		return NewError(fsm.current, NilExitCondition, fsm.next, "next state ID does not exist in states map")
 	}
 
-	fsm.state = factory()
-	if err := fsm.state.Enter(ctx); err != nil {
+	// The scopes of exited states are kept until a state has been entered, so that a failed transition does
+	// not unload the resources it shares with the state it is retried with.
+	next := factory()
+	if err := next.Enter(ctx); err != nil {
+		if scoped, ok := next.(Scoped); ok {
+			releaseScope(scoped.Scope())
+		}
 		return NewError(fsm.current, NilExitCondition, fsm.next, err.Error())
 	}
 
+	fsm.state = next
 	fsm.current = fsm.next
 	fsm.next = NilStateID
 	fsm.isTransitioning = true
+	releaseScopes(fsm)
 
 	return nil
 }
@@ -163,3 +211,19 @@ func getNextStateID[Context any](fsm *FSM[Context], exitCondition StateExitCondi
 
 	return nextStateID, nil
 }
+
+func releaseScope(scope Scope) {
+	if scope != nil {
+		scope.Release()
+	}
+}
+
+// releaseScopes releases the scopes of the exited states, oldest first.
+func releaseScopes[Context any](fsm *FSM[Context]) {
+	exited := fsm.exited
+	fsm.exited = nil
+
+	for _, scope := range exited {
+		releaseScope(scope)
+	}
+}
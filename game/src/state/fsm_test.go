@@ -0,0 +1,104 @@
+package state_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/game/src/state"
+)
+
+type testContext struct {
+	events []string
+}
+
+type scope struct {
+	name string
+	ctx  *testContext
+}
+
+func (s *scope) Release() {
+	s.ctx.events = append(s.ctx.events, "release "+s.name)
+}
+
+type testState struct {
+	name     string
+	enterErr error
+	scope    *scope
+}
+
+func (s *testState) Enter(ctx *testContext) error {
+	s.scope = &scope{name: s.name, ctx: ctx}
+	ctx.events = append(ctx.events, "enter "+s.name)
+	return s.enterErr
+}
+
+func (s *testState) Exit(ctx *testContext) error {
+	ctx.events = append(ctx.events, "exit "+s.name)
+	return nil
+}
+
+func (s *testState) Process(ctx *testContext) (state.StateExitCondition, error) {
+	return state.NilExitCondition, nil
+}
+
+func (s *testState) Scope() state.Scope {
+	return s.scope
+}
+
+type first struct{}
+type second struct{}
+
+func TestFSMReleasesScopes(t *testing.T) {
+	ctx := &testContext{}
+	enterErr := errors.New("enter failed")
+	failures := 1
+
+	fsm := state.NewFSM[testContext]()
+	firstID := state.Register[first](fsm, func() state.State[testContext] {
+		return &testState{name: "first"}
+	})
+	secondID := state.Register[second](fsm, func() state.State[testContext] {
+		s := &testState{name: "second"}
+		if failures > 0 {
+			failures--
+			s.enterErr = enterErr
+		}
+		return s
+	})
+
+	fsm.SetNext(firstID)
+	if err := fsm.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first state's scope outlives a failed transition, and is released once the retry succeeds.
+	fsm.SetNext(secondID)
+	if err := fsm.Process(ctx); err == nil {
+		t.Fatal("got no error from a state that failed to enter")
+	}
+	if err := fsm.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsm.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"enter first",
+		"exit first",
+		"enter second",
+		"release second",
+		"enter second",
+		"release first",
+		"exit second",
+		"release second",
+	}
+	if !slices.Equal(ctx.events, want) {
+		t.Fatalf("got events %q, want %q", ctx.events, want)
+	}
+	if fsm.State() != nil {
+		t.Fatal("FSM still has a state after shutdown")
+	}
+}
@@ -0,0 +1,647 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+// Disposer releases the underlying resources held by a cached value.
+type Disposer[T any] func(asset resources.Asset, value T)
+
+// Sizer estimates the memory footprint of a cached value in bytes.
+type Sizer[T any] func(value T) int64
+
+// Reloader loads a value for an asset from its ResourceSystem.
+type Reloader[T any] func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (T, error)
+
+// CacheOptions defines configuration options for a Cache.
+type CacheOptions[T any] struct {
+	// Name identifies the cache in the metrics of the ResourceSystems it holds values for.
+	Name string
+
+	// Dispose is called once a cached value is no longer referenced by the cache or any handle.
+	//
+	// It may be nil if values do not hold resources that must be released explicitly.
+	Dispose Disposer[T]
+
+	// Budget is the number of bytes the cache may hold before least-recently-used entries are evicted.
+	//
+	// A budget of zero or less disables eviction.
+	Budget int64
+
+	// Size estimates the memory footprint of a value. It is required for eviction to take effect.
+	Size Sizer[T]
+
+	// Reload loads an evicted value again through its ResourceSystem the next time it is requested.
+	// It is also registered with every ResourceSystem the cache holds values for, so that values
+	// are reloaded when a watcher detects their asset has changed. Changed values are swapped in place
+	// by ApplyReloads.
+	//
+	// Concurrent requests for the same evicted value share a single reload. If Reload is nil, evicted
+	// values are reported as missing until they are cached again.
+	Reload Reloader[T]
+}
+
+// CacheStats describes the memory usage of a Cache.
+type CacheStats struct {
+	Entries   int    // Number of values tracked by the cache
+	Usage     int64  // Estimated bytes held by the cache
+	Budget    int64  // Bytes the cache may hold before evicting
+	Evictions uint64 // Number of values evicted since the cache was created
+	Hits      uint64 // Number of lookups that found a cached value
+	Misses    uint64 // Number of lookups that found no value, or had to reload an evicted one
+	Failures  uint64 // Number of reloads of evicted values that failed
+}
+
+// Leak describes a cached value that still has live handles.
+type Leak struct {
+	System string // Name of the ResourceSystem the asset belongs to
+	resources.HandleLeak
+}
+
+// Cache stores values decoded from assets, scoped per ResourceSystem.
+//
+// Values are keyed by both the ResourceSystem and the Asset they were loaded from, so systems with
+// clashing Asset identifiers never overwrite each other. Values are reference-counted: deleting a
+// value that is still held through a handle defers its disposal until the last handle is released.
+//
+// When a budget is configured, least-recently-used values that are neither pinned nor held through
+// a handle are evicted once the budget is exceeded, and transparently reloaded on their next access.
+//
+// Cache is safe for concurrent use by multiple goroutines.
+type Cache[T any] struct {
+	options CacheOptions[T]
+	tables  map[*resources.ResourceSystem]*resources.HandleTable[T]
+
+	lru     *list.List                 // Recency order of cached values, most recent first
+	entries map[cacheKey]*list.Element // Cached values tracked for eviction
+	pinned  map[cacheKey]struct{}      // Values that must never be evicted
+	evicted map[cacheKey]struct{}      // Values evicted that may be reloaded on access
+	scoped  map[cacheKey]int           // Number of scopes retaining each value
+	reloads map[cacheKey]*reloadCall   // Reloads of evicted values in progress
+	pending map[cacheKey]T             // Values reloaded after their asset changed, waiting for ApplyReloads
+
+	usage     int64
+	evictions uint64
+	hits      uint64
+	misses    uint64
+	failures  uint64
+
+	mu sync.Mutex
+
+	released  []releasedValue[T] // Values released by the tables while a table operation holds mu
+	deferring bool               // Whether values released by the tables are queued in released
+	releaseMu sync.Mutex         // Guards released and deferring
+}
+
+type cacheKey struct {
+	rs    *resources.ResourceSystem
+	asset resources.Asset
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	size int64
+}
+
+// releasedValue is a value released by a table of the cache, waiting to be disposed.
+type releasedValue[T any] struct {
+	asset resources.Asset
+	value T
+}
+
+// reloadCall is a reload of an evicted value shared by every lookup of the value made while it runs.
+type reloadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewCache creates a new Cache with the given options.
+func NewCache[T any](options CacheOptions[T]) *Cache[T] {
+	return &Cache[T]{
+		options: options,
+		tables:  make(map[*resources.ResourceSystem]*resources.HandleTable[T]),
+		lru:     list.New(),
+		entries: make(map[cacheKey]*list.Element),
+		pinned:  make(map[cacheKey]struct{}),
+		evicted: make(map[cacheKey]struct{}),
+		scoped:  make(map[cacheKey]int),
+		reloads: make(map[cacheKey]*reloadCall),
+		pending: make(map[cacheKey]T),
+	}
+}
+
+// Get returns the cached value for the asset without acquiring a handle to it.
+//
+// If the value was evicted and the cache has a reloader, the value is reloaded before being returned, as
+// described by GetContext. A value that fails to reload is reported as missing.
+func (c *Cache[T]) Get(rs *resources.ResourceSystem, asset resources.Asset) (T, bool) {
+	value, exists, _ := c.GetContext(context.Background(), rs, asset)
+	return value, exists
+}
+
+// GetContext behaves like Get, but returns the error of a failed reload and stops waiting for the reload
+// when the context is cancelled.
+//
+// Reloads run on the calling goroutine. Callers on the game loop should pass a context with a deadline,
+// or pin the values they need every frame so that they are never evicted.
+func (c *Cache[T]) GetContext(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (T, bool, error) {
+	var zero T
+
+	table, exists, err := c.touch(ctx, rs, asset)
+	if !exists {
+		return zero, false, err
+	}
+
+	value, exists := table.Get(asset)
+	return value, exists, nil
+}
+
+// Cached returns the cached value for the asset without acquiring a handle to it. Unlike Get, evicted values
+// are reported as missing rather than reloaded, so Cached may be called while holding the lock of the asset.
+// Like other lookups, it is counted in the statistics of the cache and the metrics of the ResourceSystem.
+func (c *Cache[T]) Cached(rs *resources.ResourceSystem, asset resources.Asset) (T, bool) {
+	c.mu.Lock()
+	table, exists := c.tables[rs]
+	_, wasEvicted := c.evicted[cacheKey{rs: rs, asset: asset}]
+	hit := exists && !wasEvicted && table.Refs(asset) > 0
+	c.count(hit)
+	c.mu.Unlock()
+
+	rs.RecordCacheLookup(c.options.Name, asset, hit)
+
+	if !hit {
+		var zero T
+		return zero, false
+	}
+	return table.Get(asset)
+}
+
+// Set caches the value for the asset, replacing any previously cached value. The previous value is disposed
+// once the handles that were live when it was replaced have been released.
+func (c *Cache[T]) Set(rs *resources.ResourceSystem, asset resources.Asset, value T) {
+	c.lockTables()
+	defer c.unlockTables()
+
+	table, exists := c.tables[rs]
+	if !exists {
+		table = resources.NewHandleTable(c.release)
+		c.tables[rs] = table
+
+		if c.options.Reload != nil {
+			rs.AddReloader(c.reload)
+		}
+	}
+
+	key := cacheKey{rs: rs, asset: asset}
+	delete(c.evicted, key)
+
+	size := int64(0)
+	if c.options.Size != nil {
+		size = c.options.Size(value)
+	}
+
+	if element, exists := c.entries[key]; exists {
+		entry := element.Value.(*cacheEntry)
+		c.usage -= entry.size
+		entry.size = size
+		c.lru.MoveToFront(element)
+	} else {
+		c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	}
+	c.usage += size
+
+	// The value is stored along with its entry, so that concurrent evictions never see one without the other.
+	// The previous value and evicted values are disposed once the cache is unlocked.
+	table.Store(asset, value)
+	c.evict()
+}
+
+// Delete removes the value for the asset from the cache.
+//
+// The value is disposed once every handle acquired for it has been released.
+func (c *Cache[T]) Delete(rs *resources.ResourceSystem, asset resources.Asset) {
+	c.lockTables()
+	defer c.unlockTables()
+
+	key := cacheKey{rs: rs, asset: asset}
+	delete(c.evicted, key)
+
+	if element, exists := c.entries[key]; exists {
+		c.usage -= element.Value.(*cacheEntry).size
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+
+	if table, exists := c.tables[rs]; exists {
+		table.Delete(asset)
+	}
+}
+
+// Retain records the value for the asset in the scope, so that it is deleted once the last scope retaining
+// it is released. Retaining a value again in the same scope has no effect.
+//
+// Values should be retained before they are loaded: a value that is already cached when it is first retained
+// by a scope was loaded outside of any scope, and is shared rather than retained.
+func (c *Cache[T]) Retain(scope *Scope, rs *resources.ResourceSystem, asset resources.Asset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey{rs: rs, asset: asset}
+
+	_, cached := c.entries[key]
+	_, wasEvicted := c.evicted[key]
+	if (cached || wasEvicted) && c.scoped[key] == 0 {
+		return
+	}
+
+	if scope.retain(scopeKey{cache: c, rs: rs, asset: asset}, func() { c.releaseScoped(key) }) {
+		c.scoped[key]++
+	}
+}
+
+// RetainContext retains the value for the asset in the scope carried by ctx, as described by Retain. It has no
+// effect if ctx carries no scope.
+//
+// Caches registering a resources.Loader use it as the Retain function of the loader, so that values loaded
+// with a context carrying a scope are retained in the scope automatically.
+func (c *Cache[T]) RetainContext(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) {
+	if scope, ok := ScopeFrom(ctx); ok {
+		c.Retain(scope, rs, asset)
+	}
+}
+
+// releaseScoped releases a value retained by a scope, deleting it once no scope retains it.
+func (c *Cache[T]) releaseScoped(key cacheKey) {
+	c.mu.Lock()
+	c.scoped[key]--
+	remaining := c.scoped[key]
+	if remaining <= 0 {
+		delete(c.scoped, key)
+	}
+	c.mu.Unlock()
+
+	if remaining <= 0 {
+		c.Delete(key.rs, key.asset)
+	}
+}
+
+// Acquire returns a reference-counted handle to the cached value for the asset.
+//
+// The value is kept alive until the handle is released, even if the asset is deleted from the cache,
+// and is never evicted while the handle is alive. Evicted values are reloaded as described by Get.
+func (c *Cache[T]) Acquire(rs *resources.ResourceSystem, asset resources.Asset) (*resources.Handle[T], bool) {
+	handle, exists, _ := c.AcquireContext(context.Background(), rs, asset)
+	return handle, exists
+}
+
+// AcquireContext behaves like Acquire, but reloads evicted values as described by GetContext.
+func (c *Cache[T]) AcquireContext(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*resources.Handle[T], bool, error) {
+	table, exists, err := c.touch(ctx, rs, asset)
+	if !exists {
+		return nil, false, err
+	}
+
+	handle, exists := table.Acquire(asset)
+	return handle, exists, nil
+}
+
+// Pin prevents the value for the asset from being evicted, whether or not it is currently cached.
+func (c *Cache[T]) Pin(rs *resources.ResourceSystem, asset resources.Asset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pinned[cacheKey{rs: rs, asset: asset}] = struct{}{}
+}
+
+// Unpin allows the value for the asset to be evicted again.
+func (c *Cache[T]) Unpin(rs *resources.ResourceSystem, asset resources.Asset) {
+	c.lockTables()
+	defer c.unlockTables()
+
+	delete(c.pinned, cacheKey{rs: rs, asset: asset})
+	c.evict()
+}
+
+// SetBudget changes the number of bytes the cache may hold, evicting values if the new budget is exceeded.
+//
+// A budget of zero or less disables eviction.
+func (c *Cache[T]) SetBudget(budget int64) {
+	c.lockTables()
+	defer c.unlockTables()
+
+	c.options.Budget = budget
+	c.evict()
+}
+
+// Stats returns the current memory usage of the cache.
+func (c *Cache[T]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Entries:   len(c.entries),
+		Usage:     c.usage,
+		Budget:    c.options.Budget,
+		Evictions: c.evictions,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Failures:  c.failures,
+	}
+}
+
+// Range calls fn for each cached value, stopping early if fn returns false.
+//
+// fn must not call other methods on the cache.
+func (c *Cache[T]) Range(fn func(rs *resources.ResourceSystem, asset resources.Asset, value T) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for rs, table := range c.tables {
+		proceed := true
+		table.Range(func(asset resources.Asset, value T) bool {
+			proceed = fn(rs, asset, value)
+			return proceed
+		})
+		if !proceed {
+			return
+		}
+	}
+}
+
+// Leaks reports the cached values that still have live handles, sorted by system name.
+func (c *Cache[T]) Leaks() []Leak {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	leaks := make([]Leak, 0)
+	for rs, table := range c.tables {
+		for _, leak := range table.Leaks() {
+			leaks = append(leaks, Leak{
+				System:     rs.Name(),
+				HandleLeak: leak,
+			})
+		}
+	}
+
+	slices.SortStableFunc(leaks, func(a, b Leak) int {
+		return strings.Compare(a.System, b.System)
+	})
+
+	return leaks
+}
+
+// touch marks the value for the asset as recently used, reloading it first if it was evicted, and counts
+// the lookup as a hit or a miss in the statistics of the cache and the metrics of the ResourceSystem.
+// Concurrent lookups of an evicted value wait for the first of them to reload it, rather than reloading it
+// again. Only the cancellation of its own context ends the wait of a lookup: if the lookup that started the
+// reload is cancelled, the lookups that shared it reload the value themselves.
+//
+// touch returns the table for the asset's ResourceSystem if it may hold a value for the asset, or the error
+// of the reload if it failed.
+func (c *Cache[T]) touch(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*resources.HandleTable[T], bool, error) {
+	key := cacheKey{rs: rs, asset: asset}
+
+	c.mu.Lock()
+	table, exists := c.tables[rs]
+	if element, tracked := c.entries[key]; tracked {
+		c.lru.MoveToFront(element)
+	}
+	_, wasEvicted := c.evicted[key]
+	hit := exists && !wasEvicted && table.Refs(asset) > 0
+	c.count(hit)
+	c.mu.Unlock()
+
+	rs.RecordCacheLookup(c.options.Name, asset, hit)
+
+	if !wasEvicted || c.options.Reload == nil {
+		return table, exists, nil
+	}
+
+	for {
+		c.mu.Lock()
+		if _, evicted := c.evicted[key]; !evicted {
+			table, exists = c.tables[rs]
+			c.mu.Unlock()
+			return table, exists, nil
+		}
+
+		call, shared := c.reloads[key]
+		if !shared {
+			call = &reloadCall{done: make(chan struct{})}
+			c.reloads[key] = call
+		}
+		c.mu.Unlock()
+
+		if !shared {
+			c.reloadEvicted(ctx, key, call)
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+
+		if call.err == nil {
+			c.mu.Lock()
+			table, exists = c.tables[rs]
+			c.mu.Unlock()
+			return table, exists, nil
+		}
+		if shared && ctx.Err() == nil && isContextError(call.err) {
+			// The lookup that started the reload was cancelled, but this one was not.
+			continue
+		}
+		return nil, false, call.err
+	}
+}
+
+// isContextError reports whether err is the error of a cancelled or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// count counts a lookup as a hit or a miss. It must be called with c.mu held.
+func (c *Cache[T]) count(hit bool) {
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// reloadEvicted reloads an evicted value on behalf of every lookup sharing the call.
+func (c *Cache[T]) reloadEvicted(ctx context.Context, key cacheKey, call *reloadCall) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.reloads, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	value, err := c.options.Reload(ctx, key.rs, key.asset)
+	if err != nil {
+		c.mu.Lock()
+		c.failures++
+		c.mu.Unlock()
+
+		call.err = err
+		return
+	}
+
+	c.Set(key.rs, key.asset, value)
+}
+
+// ApplyReloads swaps the values reloaded since the last call into the cache, replacing the cached values in
+// place so that existing handles observe the new values. It returns the number of values swapped.
+//
+// Watchers reload changed assets on their own goroutine, while the previous values may still be in use. The
+// game loop should call ApplyReloads once per frame, between drawing frames, so that values are never
+// swapped or disposed while they are being drawn. Previous values are disposed as described by Set.
+func (c *Cache[T]) ApplyReloads() int {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+
+	pending := c.pending
+	c.pending = make(map[cacheKey]T)
+
+	applied := make(map[cacheKey]T, len(pending))
+	discarded := make(map[cacheKey]T)
+	for key, value := range pending {
+		if _, tracked := c.entries[key]; tracked {
+			applied[key] = value
+		} else {
+			discarded[key] = value
+		}
+	}
+	c.mu.Unlock()
+
+	for key, value := range applied {
+		c.Set(key.rs, key.asset, value)
+	}
+	for key, value := range discarded {
+		c.dispose(key.asset, value)
+	}
+
+	return len(applied)
+}
+
+// reload loads a changed asset again if the cache currently holds a value for it. The new value is kept
+// aside until ApplyReloads swaps it in.
+func (c *Cache[T]) reload(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) error {
+	key := cacheKey{rs: rs, asset: asset}
+
+	c.mu.Lock()
+	_, tracked := c.entries[key]
+	c.mu.Unlock()
+
+	if !tracked {
+		return nil
+	}
+
+	value, err := c.options.Reload(ctx, rs, asset)
+	if err != nil {
+		return err
+	}
+
+	// A value reloaded earlier but never applied has not been handed out, so it can be disposed right away.
+	c.mu.Lock()
+	previous, replaced := c.pending[key]
+	c.pending[key] = value
+	c.mu.Unlock()
+
+	if replaced {
+		c.dispose(key.asset, previous)
+	}
+
+	return nil
+}
+
+// dispose disposes a value the cache and its handles no longer reference.
+func (c *Cache[T]) dispose(asset resources.Asset, value T) {
+	if c.options.Dispose != nil {
+		c.options.Dispose(asset, value)
+	}
+}
+
+// evict removes least-recently-used values until the cache fits within its budget, deleting them from their
+// tables.
+//
+// Pinned values, values held through a handle and the most recently used value are never evicted.
+// evict must be called between lockTables and unlockTables.
+func (c *Cache[T]) evict() {
+	if c.options.Budget <= 0 {
+		return
+	}
+
+	element := c.lru.Back()
+	for element != nil && element != c.lru.Front() && c.usage > c.options.Budget {
+		prev := element.Prev()
+		entry := element.Value.(*cacheEntry)
+
+		_, isPinned := c.pinned[entry.key]
+		table := c.tables[entry.key.rs]
+
+		if !isPinned && table.Refs(entry.key.asset) <= 1 {
+			c.usage -= entry.size
+			c.evictions++
+			c.lru.Remove(element)
+			delete(c.entries, entry.key)
+			c.evicted[entry.key] = struct{}{}
+
+			table.Delete(entry.key.asset)
+		}
+
+		element = prev
+	}
+}
+
+// lockTables locks the cache for an operation that stores or deletes values in its tables. Values released
+// by the tables until unlockTables is called are disposed once the cache is unlocked, so that disposers may
+// use the cache.
+func (c *Cache[T]) lockTables() {
+	c.mu.Lock()
+
+	c.releaseMu.Lock()
+	c.deferring = true
+	c.releaseMu.Unlock()
+}
+
+// unlockTables unlocks the cache locked by lockTables and disposes the values released in the meantime.
+func (c *Cache[T]) unlockTables() {
+	c.releaseMu.Lock()
+	c.deferring = false
+	released := c.released
+	c.released = nil
+	c.releaseMu.Unlock()
+
+	c.mu.Unlock()
+
+	for _, r := range released {
+		c.dispose(r.asset, r.value)
+	}
+}
+
+// release is the release function of the tables of the cache. Values released while a table operation holds
+// the cache lock, including values whose last handle is released meanwhile, are disposed by that operation
+// once it unlocks the cache.
+func (c *Cache[T]) release(asset resources.Asset, value T) {
+	c.releaseMu.Lock()
+	if c.deferring {
+		c.released = append(c.released, releasedValue[T]{asset: asset, value: value})
+		c.releaseMu.Unlock()
+		return
+	}
+	c.releaseMu.Unlock()
+
+	c.dispose(asset, value)
+}
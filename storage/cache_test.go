@@ -0,0 +1,474 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/engine/resources/resourcestest"
+	"github.com/adm87/flinch/storage"
+)
+
+type value struct {
+	asset resources.Asset
+	gen   int64
+}
+
+func newSystem(t *testing.T, n int) (*resources.ResourceSystem, []resources.Asset) {
+	t.Helper()
+
+	paths := make([]string, n)
+	for i := range n {
+		paths[i] = fmt.Sprintf("assets/file%02d.bin", i)
+	}
+	files, assets := resourcestest.Files(paths...)
+	return resourcestest.NewSystem(t.Name(), files), assets
+}
+
+func TestCacheScopesValuesPerSystem(t *testing.T) {
+	first, assets := newSystem(t, 1)
+	second, _ := newSystem(t, 1)
+
+	cache := storage.NewCache(storage.CacheOptions[*value]{})
+
+	// Both systems identify the same file with the same asset.
+	cache.Set(first, assets[0], &value{asset: assets[0], gen: 1})
+	cache.Set(second, assets[0], &value{asset: assets[0], gen: 2})
+
+	for rs, gen := range map[*resources.ResourceSystem]int64{first: 1, second: 2} {
+		if v, exists := cache.Get(rs, assets[0]); !exists || v.gen != gen {
+			t.Fatalf("got %v, want the value of generation %d", v, gen)
+		}
+	}
+
+	cache.Delete(first, assets[0])
+	if _, exists := cache.Get(first, assets[0]); exists {
+		t.Fatal("value not deleted")
+	}
+	if v, exists := cache.Get(second, assets[0]); !exists || v.gen != 2 {
+		t.Fatalf("deleting the value of one system deleted the value of the other: %v", v)
+	}
+}
+
+func TestCacheDeleteDisposesValue(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	disposed := make([]*value, 0)
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Dispose: func(asset resources.Asset, v *value) {
+			if v.asset != asset {
+				t.Errorf("value of asset %d disposed for asset %d", v.asset, asset)
+			}
+			disposed = append(disposed, v)
+		},
+	})
+
+	unheld, held := &value{asset: assets[0]}, &value{asset: assets[1]}
+	cache.Set(rs, assets[0], unheld)
+	cache.Set(rs, assets[1], held)
+
+	cache.Delete(rs, assets[0])
+	if !slices.Equal(disposed, []*value{unheld}) {
+		t.Fatalf("got disposals %v, want the deleted value", disposed)
+	}
+
+	// A value still held through a handle is disposed once the handle is released.
+	handle, _ := cache.Acquire(rs, assets[1])
+	cache.Delete(rs, assets[1])
+	if len(disposed) != 1 {
+		t.Fatalf("got disposals %v, want none while a handle is live", disposed)
+	}
+
+	handle.Release()
+	if !slices.Equal(disposed, []*value{unheld, held}) {
+		t.Fatalf("got disposals %v, want the held value once its handle was released", disposed)
+	}
+}
+
+func TestCacheReloadsEvictedValueOnce(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	var reloads atomic.Int64
+	release := make(chan struct{})
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			<-release
+			return &value{asset: asset, gen: reloads.Add(1)}, nil
+		},
+	})
+
+	cache.Set(rs, assets[0], &value{asset: assets[0]})
+	cache.Set(rs, assets[1], &value{asset: assets[1]})
+	if cache.Stats().Evictions != 1 {
+		t.Fatalf("got %d evictions, want 1", cache.Stats().Evictions)
+	}
+
+	got := make([]*value, 8)
+	wg := sync.WaitGroup{}
+	for i := range got {
+		wg.Go(func() {
+			got[i], _ = cache.Get(rs, assets[0])
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if reloads.Load() != 1 {
+		t.Fatalf("evicted value reloaded %d times, want 1", reloads.Load())
+	}
+	for _, v := range got {
+		if v == nil || v != got[0] {
+			t.Fatalf("lookups returned different values: %v", got)
+		}
+	}
+}
+
+func TestCacheReportsReloadErrors(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	reloadErr := errors.New("reload failed")
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			return nil, reloadErr
+		},
+	})
+
+	cache.Set(rs, assets[0], &value{asset: assets[0]})
+	cache.Set(rs, assets[1], &value{asset: assets[1]})
+
+	if _, exists, err := cache.GetContext(context.Background(), rs, assets[0]); exists || !errors.Is(err, reloadErr) {
+		t.Fatalf("got (%v, %v), want the reload error", exists, err)
+	}
+	if _, exists, err := cache.AcquireContext(context.Background(), rs, assets[0]); exists || !errors.Is(err, reloadErr) {
+		t.Fatalf("got (%v, %v), want the reload error", exists, err)
+	}
+	if failures := cache.Stats().Failures; failures != 2 {
+		t.Fatalf("got %d failures, want 2", failures)
+	}
+}
+
+func TestCacheReloadHonoursContext(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	cache.Set(rs, assets[0], &value{asset: assets[0]})
+	cache.Set(rs, assets[1], &value{asset: assets[1]})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := cache.GetContext(ctx, rs, assets[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCacheSharedReloadOutlivesCancelledLookup(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	var reloads atomic.Int64
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			started <- struct{}{}
+			select {
+			case <-release:
+				return &value{asset: asset, gen: reloads.Add(1)}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	})
+
+	cache.Set(rs, assets[0], &value{asset: assets[0]})
+	cache.Set(rs, assets[1], &value{asset: assets[1]})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := cache.GetContext(ctx, rs, assets[0])
+		first <- err
+	}()
+	<-started
+
+	second := make(chan *value, 1)
+	go func() {
+		v, _, err := cache.GetContext(context.Background(), rs, assets[0])
+		if err != nil {
+			t.Error(err)
+		}
+		second <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+
+	// The lookup that shared the cancelled reload reloads the value itself.
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("lookup sharing the cancelled reload did not reload the value")
+	}
+	close(release)
+
+	if v := <-second; v == nil || v.asset != assets[0] {
+		t.Fatalf("got %v, want the reloaded value", v)
+	}
+	if reloads.Load() != 1 {
+		t.Fatalf("value reloaded %d times, want 1", reloads.Load())
+	}
+}
+
+func TestCacheConcurrentSetsTrackStoredValues(t *testing.T) {
+	rs, assets := newSystem(t, 16)
+
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 4,
+		Size:   func(*value) int64 { return 1 },
+	})
+
+	wg := sync.WaitGroup{}
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 200 {
+				asset := assets[(i+j)%len(assets)]
+				cache.Set(rs, asset, &value{asset: asset})
+				if j%50 == 0 {
+					cache.SetBudget(int64(2 + j%3))
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	stored := 0
+	cache.Range(func(*resources.ResourceSystem, resources.Asset, *value) bool {
+		stored++
+		return true
+	})
+	if stats := cache.Stats(); stored != stats.Entries {
+		t.Fatalf("got %d stored values, want the %d values tracked by the cache", stored, stats.Entries)
+	}
+}
+
+func TestCacheDisposesOutsideLock(t *testing.T) {
+	rs, assets := newSystem(t, 3)
+
+	var cache *storage.Cache[*value]
+	disposed := make([]resources.Asset, 0)
+	cache = storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Dispose: func(asset resources.Asset, v *value) {
+			// Disposers may call back into the cache.
+			cache.Stats()
+			cache.Delete(rs, assets[2])
+			disposed = append(disposed, asset)
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		cache.Set(rs, assets[0], &value{asset: assets[0]})
+		cache.Set(rs, assets[1], &value{asset: assets[1]})
+		cache.Set(rs, assets[1], &value{asset: assets[1], gen: 1})
+		cache.SetBudget(0)
+		cache.Delete(rs, assets[1])
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("disposer calling into the cache deadlocked")
+	}
+
+	if len(disposed) != 3 {
+		t.Fatalf("got %d disposals, want 3", len(disposed))
+	}
+}
+
+func TestCacheAppliesReloadsOnDemand(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	var gen atomic.Int64
+	disposed := make(map[*value]bool)
+	cache := storage.NewCache(storage.CacheOptions[*value]{
+		Dispose: func(asset resources.Asset, v *value) {
+			disposed[v] = true
+		},
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			return &value{asset: asset, gen: gen.Add(1)}, nil
+		},
+	})
+
+	original := &value{asset: assets[0]}
+	cache.Set(rs, assets[0], original)
+
+	// Two changes detected before the game loop applies them: only the latest value is swapped in.
+	for range 2 {
+		if err := rs.Reload(context.Background(), assets[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Assets the cache does not hold are not reloaded.
+	if err := rs.Reload(context.Background(), assets[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := cache.Get(rs, assets[0]); v != original {
+		t.Fatalf("reloaded value swapped in before ApplyReloads: %v", v)
+	}
+	if disposed[original] {
+		t.Fatal("cached value disposed before ApplyReloads")
+	}
+
+	if applied := cache.ApplyReloads(); applied != 1 {
+		t.Fatalf("applied %d reloads, want 1", applied)
+	}
+	v, _ := cache.Get(rs, assets[0])
+	if v.gen != 2 {
+		t.Fatalf("got generation %d, want 2", v.gen)
+	}
+	if !disposed[original] || len(disposed) != 2 {
+		t.Fatalf("got disposals %v, want the original and the superseded reload", disposed)
+	}
+	if _, exists := cache.Get(rs, assets[1]); exists {
+		t.Fatal("reload cached an asset the cache did not hold")
+	}
+	if applied := cache.ApplyReloads(); applied != 0 {
+		t.Fatalf("applied %d reloads again, want 0", applied)
+	}
+}
+
+func TestCacheBackedLoaderReloadsEvictedValues(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	var decodes atomic.Int64
+	decode := func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+		if _, err := rs.ReadBytesContext(ctx, asset); err != nil {
+			return nil, err
+		}
+		return &value{asset: asset, gen: decodes.Add(1)}, nil
+	}
+
+	var cache *storage.Cache[*value]
+	cache = storage.NewCache(storage.CacheOptions[*value]{
+		Budget: 1,
+		Size:   func(*value) int64 { return 1 },
+		Reload: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			lock, err := rs.LockAssetContext(ctx, resources.NewBatchID(), asset)
+			if err != nil {
+				return nil, err
+			}
+			defer lock.Release()
+			return decode(ctx, rs, asset)
+		},
+	})
+	resources.Register(resources.Loader[*value]{
+		Decode: decode,
+		Store:  cache.Set,
+		Cached: cache.Cached,
+	}, ".bin")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, asset := range []resources.Asset{assets[0], assets[1], assets[0], assets[0]} {
+		v, err := resources.Load[*value](ctx, rs, asset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.asset != asset {
+			t.Fatalf("loaded asset %d, want %d", v.asset, asset)
+		}
+	}
+
+	// The first asset was evicted by the second, decoded again, and then served from the cache.
+	if decodes.Load() != 3 {
+		t.Fatalf("decoded %d times, want 3", decodes.Load())
+	}
+}
+
+func TestCacheReportsLookups(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	report := resources.NewMetricsReport(resources.MetricsReportOptions{})
+	rs.SetMetrics(report)
+
+	cache := storage.NewCache(storage.CacheOptions[*value]{Name: "values"})
+	cache.Set(rs, assets[0], &value{asset: assets[0]})
+
+	cache.Get(rs, assets[0])
+	cache.Cached(rs, assets[0])
+	cache.Get(rs, assets[1])
+
+	want := []resources.CacheSummary{{System: rs.Name(), Cache: "values", Hits: 2, Misses: 1}}
+	if got := report.Caches(); !slices.Equal(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("got %d hits and %d misses, want 2 and 1", stats.Hits, stats.Misses)
+	}
+}
+
+func TestScopeRetainsValuesLoadedThroughContext(t *testing.T) {
+	rs, assets := newSystem(t, 2)
+
+	cache := storage.NewCache(storage.CacheOptions[*value]{})
+	resources.Register(resources.Loader[*value]{
+		Decode: func(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*value, error) {
+			return &value{asset: asset}, nil
+		},
+		Store:  cache.Set,
+		Cached: cache.Cached,
+		Retain: cache.RetainContext,
+	}, ".bin")
+
+	scope := storage.NewScope("scoped")
+	if _, err := resources.Load[*value](storage.WithScope(context.Background(), scope), rs, assets[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resources.Load[*value](context.Background(), rs, assets[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if scope.Len() != 1 {
+		t.Fatalf("scope retains %d values, want 1", scope.Len())
+	}
+
+	scope.Release()
+	if _, exists := cache.Get(rs, assets[0]); exists {
+		t.Fatal("value loaded through the scope not deleted with it")
+	}
+	if _, exists := cache.Get(rs, assets[1]); !exists {
+		t.Fatal("value loaded outside of the scope deleted with it")
+	}
+}
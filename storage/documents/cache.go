@@ -0,0 +1,103 @@
+// Package documents caches the contents of data assets, such as Tiled maps and tilesets and Aseprite
+// sprite sheet data, on behalf of the resources loader registry.
+//
+// Documents are kept as the raw bytes of their file: the engine does not decode these formats yet, and
+// code using them parses the cached contents itself. A package that decodes one of the formats should
+// register its own resources.Loader for the extension, which replaces the one registered here.
+package documents
+
+import (
+	"context"
+
+	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/storage"
+)
+
+// Extensions lists the file extensions of the assets cached as documents.
+var Extensions = []string{".json", ".tmx", ".tsx"}
+
+var (
+	cache = storage.NewCache(storage.CacheOptions[[]byte]{
+		Name: "documents",
+		Size: func(data []byte) int64 {
+			return int64(len(data))
+		},
+		Reload: reloadDocument,
+	})
+)
+
+func init() {
+	resources.Register(resources.Loader[[]byte]{
+		Decode: decodeDocument,
+		Store:  Set,
+		Cached: cache.Cached,
+		Retain: cache.RetainContext,
+	}, Extensions...)
+}
+
+// Get returns the cached contents of the document for the asset without acquiring a handle to them.
+func Get(rs *resources.ResourceSystem, asset resources.Asset) ([]byte, bool) {
+	return cache.Get(rs, asset)
+}
+
+// Set caches the contents of the document for the asset, replacing any previously cached contents.
+func Set(rs *resources.ResourceSystem, asset resources.Asset, data []byte) {
+	cache.Set(rs, asset, data)
+}
+
+// Delete removes the document for the asset from the cache.
+func Delete(rs *resources.ResourceSystem, asset resources.Asset) {
+	cache.Delete(rs, asset)
+}
+
+// Acquire returns a reference-counted handle to the cached contents of the document for the asset.
+func Acquire(rs *resources.ResourceSystem, asset resources.Asset) (*resources.Handle[[]byte], bool) {
+	return cache.Acquire(rs, asset)
+}
+
+// Retain records the documents for the assets in the scope, so that they are deleted once the last scope
+// retaining them is released, as described by storage.Cache.Retain. Documents loaded with a context carrying
+// the scope are retained automatically.
+func Retain(scope *storage.Scope, rs *resources.ResourceSystem, assets ...resources.Asset) {
+	for _, asset := range assets {
+		cache.Retain(scope, rs, asset)
+	}
+}
+
+// ApplyReloads swaps the documents reloaded since the last call into the cache. It must be called once per
+// frame from the game loop when hot reloading is enabled, as described by storage.Cache.ApplyReloads.
+func ApplyReloads() int {
+	return cache.ApplyReloads()
+}
+
+// SetBudget sets the number of bytes of document contents the cache may hold before least-recently-used
+// documents are evicted. A budget of zero or less disables eviction, which is the default.
+func SetBudget(budget int64) {
+	cache.SetBudget(budget)
+}
+
+// Stats returns the current memory usage of the document cache.
+func Stats() storage.CacheStats {
+	return cache.Stats()
+}
+
+// Leaks reports the documents that still have live handles.
+func Leaks() []storage.Leak {
+	return cache.Leaks()
+}
+
+// reloadDocument loads a document again on behalf of the cache.
+func reloadDocument(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) ([]byte, error) {
+	lock, err := rs.LockAssetContext(ctx, resources.NewBatchID(), asset)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	return decodeDocument(ctx, rs, asset)
+}
+
+// decodeDocument reads the contents of a document. The caller must hold the lock for the asset.
+func decodeDocument(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) ([]byte, error) {
+	return rs.ReadBytesContext(ctx, asset)
+}
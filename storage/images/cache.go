@@ -2,72 +2,153 @@ package images
 
 import (
 	"bytes"
-	"sync"
+	"context"
 
-	"github.com/adm87/flinch/engine/flinch"
 	"github.com/adm87/flinch/engine/resources"
+	"github.com/adm87/flinch/storage"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 var (
-	cache = make(map[resources.Asset]*ebiten.Image)
-	mu    = sync.RWMutex{}
+	cache = storage.NewCache(storage.CacheOptions[*ebiten.Image]{
+		Name: "images",
+		Dispose: func(asset resources.Asset, img *ebiten.Image) {
+			img.Deallocate()
+		},
+		Size: func(img *ebiten.Image) int64 {
+			// Images are stored as 32-bit RGBA on the GPU.
+			return int64(img.Bounds().Dx()) * int64(img.Bounds().Dy()) * 4
+		},
+		Reload: reloadImage,
+	})
 )
 
-func Get(asset resources.Asset) (*ebiten.Image, bool) {
-	mu.RLock()
-	defer mu.RUnlock()
+func init() {
+	resources.Register(resources.Loader[*ebiten.Image]{
+		Decode: decodeImage,
+		Store:  Set,
+		Cached: cache.Cached,
+		Retain: cache.RetainContext,
+	}, ".png")
+}
+
+// Get returns the cached image for the asset without acquiring a handle to it.
+func Get(rs *resources.ResourceSystem, asset resources.Asset) (*ebiten.Image, bool) {
+	return cache.Get(rs, asset)
+}
+
+// GetContext behaves like Get, but returns the error of a failed reload of an evicted image and stops
+// waiting for the reload when the context is cancelled.
+func GetContext(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*ebiten.Image, bool, error) {
+	return cache.GetContext(ctx, rs, asset)
+}
+
+// Set caches the image for the asset, replacing any previously cached image. The previous image is
+// deallocated once the handles that were live when it was replaced have been released.
+func Set(rs *resources.ResourceSystem, asset resources.Asset, img *ebiten.Image) {
+	cache.Set(rs, asset, img)
+}
 
-	img, exists := cache[asset]
-	return img, exists
+// Delete removes the image for the asset from the cache.
+//
+// The image is deallocated once every handle acquired for it has been released.
+func Delete(rs *resources.ResourceSystem, asset resources.Asset) {
+	cache.Delete(rs, asset)
 }
 
-func Set(asset resources.Asset, img *ebiten.Image) {
-	mu.Lock()
-	defer mu.Unlock()
+// Acquire returns a reference-counted handle to the cached image for the asset.
+//
+// The image is kept alive until the handle is released, even if the asset is deleted from the cache.
+func Acquire(rs *resources.ResourceSystem, asset resources.Asset) (*resources.Handle[*ebiten.Image], bool) {
+	return cache.Acquire(rs, asset)
+}
+
+// AcquireContext behaves like Acquire, but returns the error of a failed reload of an evicted image and
+// stops waiting for the reload when the context is cancelled.
+func AcquireContext(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*resources.Handle[*ebiten.Image], bool, error) {
+	return cache.AcquireContext(ctx, rs, asset)
+}
 
-	cache[asset] = img
+// Range calls fn for each cached image, stopping early if fn returns false.
+func Range(fn func(rs *resources.ResourceSystem, asset resources.Asset, img *ebiten.Image) bool) {
+	cache.Range(fn)
 }
 
-func Delete(asset resources.Asset) {
-	mu.Lock()
-	defer mu.Unlock()
+// Pin prevents the image for the asset from being evicted when the cache exceeds its budget.
+func Pin(rs *resources.ResourceSystem, asset resources.Asset) {
+	cache.Pin(rs, asset)
+}
 
-	if img, exists := cache[asset]; exists {
-		img.Deallocate()
-		delete(cache, asset)
+// Unpin allows the image for the asset to be evicted again.
+func Unpin(rs *resources.ResourceSystem, asset resources.Asset) {
+	cache.Unpin(rs, asset)
+}
+
+// Retain records the images for the assets in the scope, so that they are deleted once the last scope
+// retaining them is released. Images should be retained before they are loaded, as described by
+// storage.Cache.Retain. Images loaded with a context carrying the scope are retained automatically.
+func Retain(scope *storage.Scope, rs *resources.ResourceSystem, assets ...resources.Asset) {
+	for _, asset := range assets {
+		cache.Retain(scope, rs, asset)
 	}
 }
 
+// ApplyReloads swaps the images reloaded since the last call into the cache, deallocating the previous
+// images once no handle uses them. It must be called once per frame from the game loop when hot reloading
+// is enabled, as described by storage.Cache.ApplyReloads.
+func ApplyReloads() int {
+	return cache.ApplyReloads()
+}
+
+// SetBudget sets the number of bytes of image data the cache may hold before least-recently-used
+// images are evicted. Evicted images are reloaded through their ResourceSystem on next access.
+//
+// A budget of zero or less disables eviction, which is the default.
+func SetBudget(budget int64) {
+	cache.SetBudget(budget)
+}
+
+// Stats returns the current memory usage of the image cache.
+func Stats() storage.CacheStats {
+	return cache.Stats()
+}
+
+// Leaks reports the images that still have live handles.
+func Leaks() []storage.Leak {
+	return cache.Leaks()
+}
+
 // NewLoader creates a new LoadingTask that loads the specified assets into the image cache.
+//
+// Images are loaded by the loader registered for their extension, exactly as with resources.NewLoader:
+// images that are already cached are not decoded again.
 func NewLoader(assets ...resources.Asset) resources.LoadingTask {
-	return func(ctx *flinch.Context, rs *resources.ResourceSystem, batchID uint64) error {
-		for _, asset := range assets {
-			if err := loadImage(rs, asset, batchID); err != nil {
-				return err
-			}
-		}
-		return nil
-	}
+	return resources.NewLoader(assets...)
 }
 
-// loadImage is a helper to maintain concurrent image loading safety.
-func loadImage(rs *resources.ResourceSystem, asset resources.Asset, batchID uint64) error {
-	lock := rs.LockAsset(batchID, asset)
+// reloadImage loads an evicted image again on behalf of the cache.
+func reloadImage(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*ebiten.Image, error) {
+	lock, err := rs.LockAssetContext(ctx, resources.NewBatchID(), asset)
+	if err != nil {
+		return nil, err
+	}
 	defer lock.Release()
 
-	data, err := rs.ReadBytes(asset)
+	return decodeImage(ctx, rs, asset)
+}
+
+// decodeImage reads and decodes an image. The caller must hold the lock for the asset.
+func decodeImage(ctx context.Context, rs *resources.ResourceSystem, asset resources.Asset) (*ebiten.Image, error) {
+	data, err := rs.ReadBytesContext(ctx, asset)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	img, _, err := ebitenutil.NewImageFromReader(bytes.NewReader(data))
 	if err != nil {
-		return err
+		return nil, rs.DecodeError(asset, err)
 	}
 
-	Set(asset, img)
-
-	return nil
+	return img, nil
 }
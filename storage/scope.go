@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/adm87/flinch/engine/flinch"
+	"github.com/adm87/flinch/engine/resources"
+)
+
+// Scope records the values retained from caches on behalf of a part of the game, such as a game state,
+// and releases all of them at once when that part of the game is done with them.
+//
+// A value may be retained by several scopes, and is deleted from its cache once the last scope retaining
+// it is released, so that values shared by two scopes stay loaded while either of them is alive. Values
+// that were already cached outside of any scope when first retained are shared with the rest of the game,
+// and are never deleted by a scope.
+//
+// Values are retained either explicitly, with the Retain function of their cache, or automatically, by
+// loading them through the resources loader registry with a context carrying the scope, as returned by
+// WithScope or Scope.Context.
+//
+// Scope is safe for concurrent use by multiple goroutines.
+type Scope struct {
+	name     string
+	retained map[scopeKey]func() // Release function of each retained value
+	order    []scopeKey          // Retained values, in the order they were retained
+	deferred []func()
+	released bool
+
+	mu sync.Mutex
+}
+
+type scopeContextKey struct{}
+
+type scopeKey struct {
+	cache any // The cache the value is retained from
+	rs    *resources.ResourceSystem
+	asset resources.Asset
+}
+
+// NewScope creates a new, empty Scope. The name identifies the scope in debugging output.
+func NewScope(name string) *Scope {
+	return &Scope{
+		name:     name,
+		retained: make(map[scopeKey]func()),
+		order:    make([]scopeKey, 0),
+		deferred: make([]func(), 0),
+	}
+}
+
+// WithScope returns a copy of ctx carrying the scope. Assets loaded through the resources loader registry with
+// the returned context are retained in the scope by the caches their loaders store them in, as if they had
+// been retained before being loaded.
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// ScopeFrom returns the scope carried by ctx, if any.
+func ScopeFrom(ctx context.Context) (*Scope, bool) {
+	scope, ok := ctx.Value(scopeContextKey{}).(*Scope)
+	return scope, ok
+}
+
+// Context returns a copy of ctx carrying the scope, as described by WithScope. Loading operations started
+// with the returned context retain the assets they load in the scope.
+func (s *Scope) Context(ctx *flinch.Context) *flinch.Context {
+	return ctx.WithContext(WithScope(ctx, s))
+}
+
+// Name returns the name of the scope.
+func (s *Scope) Name() string {
+	return s.name
+}
+
+// Len returns the number of values retained by the scope.
+func (s *Scope) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.order)
+}
+
+// Defer registers a function to be called when the scope is released, such as releasing a handle or
+// unpinning a value. Deferred functions are called in reverse order, before the retained values are released.
+//
+// Defer panics if the scope has already been released.
+func (s *Scope) Defer(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.released {
+		panic("Scope used after release. DO NOT use a Scope once it has been released.")
+	}
+	s.deferred = append(s.deferred, fn)
+}
+
+// Release calls the deferred functions of the scope and releases every value it retains. Values no
+// longer retained by any scope are deleted from their cache, and disposed once their last handle is released.
+//
+// The scope must not be released while values are still being loaded into it, or they may be cached after
+// the scope released them. Release panics if the scope has already been released.
+func (s *Scope) Release() {
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		panic("Scope released multiple times. DO NOT release a Scope more than once.")
+	}
+	s.released = true
+
+	deferred := s.deferred
+	releases := make([]func(), 0, len(s.order))
+	for _, key := range s.order {
+		releases = append(releases, s.retained[key])
+	}
+	s.deferred, s.order, s.retained = nil, nil, nil
+	s.mu.Unlock()
+
+	for _, fn := range slices.Backward(deferred) {
+		fn()
+	}
+	for _, release := range slices.Backward(releases) {
+		release()
+	}
+}
+
+// retain records a value retained by the scope, and the function that releases it. It reports whether the
+// value was not already retained by the scope.
+func (s *Scope) retain(key scopeKey, release func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.released {
+		panic("Scope used after release. DO NOT use a Scope once it has been released.")
+	}
+	if _, exists := s.retained[key]; exists {
+		return false
+	}
+
+	s.retained[key] = release
+	s.order = append(s.order, key)
+	return true
+}
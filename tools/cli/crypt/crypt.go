@@ -0,0 +1,107 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The encrypted file format is documented in the engine crypt package. Files are sealed with AES-GCM
+// using their slash-separated path as additional data, so that a moved or renamed file fails to decrypt.
+const (
+	magic   = "FLEN"
+	version = 2
+)
+
+// ParseKey decodes a hex-encoded AES key of 16, 24 or 32 bytes.
+func ParseKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid key: must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Sealer encrypts files with a key.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a Sealer for the key.
+func NewSealer(key []byte) (*Sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts the contents of the file with the given name, the slash-separated path the engine opens the
+// file with.
+func (s *Sealer) Seal(name string, data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(magic)+1+s.aead.NonceSize()+len(data)+s.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, version)
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+
+	return s.aead.Seal(out, nonce, data, []byte(name)), nil
+}
+
+// SealDir writes an encrypted copy of every file under src to the same relative path under dst.
+//
+// Files are sealed with their slash-separated path relative to src, joined to prefix, which must be the path
+// the engine opens them with. dst is removed first, so that it never holds files that no longer exist under
+// src.
+func (s *Sealer) SealDir(src, dst, prefix string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sealed, err := s.Seal(filepath.ToSlash(filepath.Join(prefix, relPath)), data)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(target, sealed, 0644)
+	})
+}
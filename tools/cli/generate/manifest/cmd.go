@@ -9,7 +9,11 @@ import (
 )
 
 func Command() *cobra.Command {
-	var output string
+	var (
+		output    string
+		serialize []string
+		key       string
+	)
 
 	model := &Model{}
 
@@ -30,6 +34,14 @@ func Command() *cobra.Command {
 				return err
 			}
 
+			ReportVariants(cmd.OutOrStdout(), model)
+
+			if key != "" {
+				if err := Encrypt(model, absPath, key); err != nil {
+					return err
+				}
+			}
+
 			content, err := GenerateFromTemplate(model)
 			if err != nil {
 				return err
@@ -39,13 +51,20 @@ func Command() *cobra.Command {
 				return err
 			}
 
-			return exec.Command("go", "fmt", filepath.Join(absPath, output)).Run()
+			if err := exec.Command("go", "fmt", filepath.Join(absPath, output)).Run(); err != nil {
+				return err
+			}
+
+			return Serialize(model, absPath, serialize)
 		},
 	}
 
 	command.Flags().StringVarP(&model.Package, "package", "p", model.Package, "Package name for the generated manifest.go file")
 	command.Flags().StringArrayVarP(&model.Embedded, "embed", "e", model.Embedded, "Directories to embed in the manifest")
 	command.Flags().StringVarP(&output, "output", "o", output, "Output path for the generated manifest.go file")
+	command.Flags().StringArrayVarP(&model.Locales, "locale", "l", model.Locales, "Locales whose suffix groups files as variants, e.g. fr or pt-BR (name.<locale>.ext)")
+	command.Flags().StringVar(&key, "key", key, "Hex-encoded AES key to encrypt the embedded directories with")
+	command.Flags().StringArrayVarP(&serialize, "serialize", "s", serialize, "Also write serialized manifests for runtime loading (json, binary)")
 
 	command.MarkFlagRequired("package")
 	command.MarkFlagRequired("output")
@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"path/filepath"
+
+	"github.com/adm87/flinch/tools/cli/crypt"
+)
+
+// encryptedDir is the directory, relative to the working directory, holding the encrypted copies of the
+// embedded directories. Scan skips it, since its name starts with an underscore.
+const encryptedDir = "_encrypted"
+
+// Encrypt writes an encrypted copy of every embedded directory of the model under the encrypted directory,
+// and marks the model as encrypted so that the generated code embeds the copies instead of the originals.
+//
+// Digests are left unchanged: they describe the decrypted contents of the files.
+func Encrypt(model *Model, directory string, key string) error {
+	aesKey, err := crypt.ParseKey(key)
+	if err != nil {
+		return err
+	}
+
+	sealer, err := crypt.NewSealer(aesKey)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range model.Directories {
+		if !dir.IsEmbedded {
+			continue
+		}
+		// Embedded directories are served with their manifest paths, which include the directory name.
+		if err := sealer.SealDir(filepath.Join(directory, dir.Path), filepath.Join(directory, encryptedDir, dir.Path), dir.Path); err != nil {
+			return err
+		}
+	}
+
+	model.Encrypted = true
+	return nil
+}
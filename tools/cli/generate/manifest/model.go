@@ -3,6 +3,8 @@ package manifest
 type Model struct {
 	Package     string
 	Embedded    []string
+	Encrypted   bool
+	Locales     []string // Locale suffixes recognised in the names of variant files
 	Directories []Directory
 }
 
@@ -14,7 +16,19 @@ type Directory struct {
 }
 
 type File struct {
-	Path string
-	Name string
-	Hash string
+	Path     string
+	Name     string
+	Hash     string
+	Size     int64
+	SHA256   string
+	Variants []Variant
+}
+
+type Variant struct {
+	Path   string
+	Hash   string
+	Locale string
+	Scale  string
+	Size   int64
+	SHA256 string
 }
@@ -30,6 +30,11 @@ func Scan(model *Model, directory string, output string) error {
 			return nil
 		}
 
+		// Directories starting with an underscore hold generated files, such as encrypted copies.
+		if strings.HasPrefix(parts[0], "_") {
+			return nil
+		}
+
 		if len(parts) < 2 {
 			return nil
 		}
@@ -51,10 +56,17 @@ func Scan(model *Model, directory string, output string) error {
 			}
 		}
 
+		size, digest, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+
 		file := File{
-			Path: relPath,
-			Name: strings.TrimSuffix(fileName, filepath.Ext(fileName)),
-			Hash: fmt.Sprintf("0x%x", HashFNV(fileName)),
+			Path:   filepath.ToSlash(relPath),
+			Name:   strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+			Hash:   fmt.Sprintf("0x%x", HashFNV(fileName)),
+			Size:   size,
+			SHA256: digest,
 		}
 
 		dir.Files = append(dir.Files, file)
@@ -69,6 +81,7 @@ func Scan(model *Model, directory string, output string) error {
 
 	model.Directories = make([]Directory, 0, len(directories))
 	for _, dir := range directories {
+		dir.Files = groupVariants(dir.Files, model.Locales)
 		slices.SortFunc(dir.Files, func(f1, f2 File) int {
 			return strings.Compare(f1.Path, f2.Path)
 		})
@@ -79,5 +92,38 @@ func Scan(model *Model, directory string, output string) error {
 		return strings.Compare(d1.Name, d2.Name)
 	})
 
+	return checkCollisions(model)
+}
+
+// checkCollisions reports files that would share an asset identifier, either because they have the same
+// file name or because their names hash to the same value. Identifiers must be unique across every
+// directory, as resource systems report identifiers shared with each other.
+func checkCollisions(model *Model) error {
+	ids := make(map[string]string)
+
+	claim := func(hash, path string) error {
+		if existing, exists := ids[hash]; exists && existing != path {
+			return fmt.Errorf("files %s and %s share the asset identifier %s", existing, path, hash)
+		}
+		ids[hash] = path
+		return nil
+	}
+
+	for _, dir := range model.Directories {
+		for _, file := range dir.Files {
+			if err := claim(file.Hash, file.Path); err != nil {
+				return err
+			}
+			for _, variant := range file.Variants {
+				if variant.Hash == file.Hash {
+					continue
+				}
+				if err := claim(variant.Hash, variant.Path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The serialized manifest formats are documented in the engine resources package.
+const (
+	FormatJSON   = "json"
+	FormatBinary = "binary"
+
+	serializedMagic   = "FLMF"
+	serializedVersion = 1
+)
+
+type jsonManifest struct {
+	Version int         `json:"version"`
+	Assets  []jsonAsset `json:"assets"`
+}
+
+type jsonAsset struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Serialize writes a serialized manifest for each directory of the model into outputDir, named after the
+// directory, e.g. assets.manifest.json or assets.manifest.bin.
+func Serialize(model *Model, outputDir string, formats []string) error {
+	for _, format := range formats {
+		for _, dir := range model.Directories {
+			var (
+				content   []byte
+				extension string
+				err       error
+			)
+
+			switch format {
+			case FormatJSON:
+				content, err = encodeJSON(dir)
+				extension = ".manifest.json"
+			case FormatBinary:
+				content, err = encodeBinary(dir)
+				extension = ".manifest.bin"
+			default:
+				return fmt.Errorf("unknown manifest format %q, expected %q or %q", format, FormatJSON, FormatBinary)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(filepath.Join(outputDir, dir.Name+extension), content, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func encodeJSON(dir Directory) ([]byte, error) {
+	document := jsonManifest{
+		Version: serializedVersion,
+		Assets:  make([]jsonAsset, 0, len(dir.Files)),
+	}
+
+	for _, file := range dir.Files {
+		document.Assets = append(document.Assets, jsonAsset{
+			ID:     file.Hash,
+			Path:   file.Path,
+			Size:   file.Size,
+			SHA256: file.SHA256,
+		})
+	}
+
+	content, err := json.MarshalIndent(document, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func encodeBinary(dir Directory) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(serializedMagic)
+	binary.Write(buf, binary.LittleEndian, uint16(serializedVersion))
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, uint32(len(dir.Files)))
+
+	for _, file := range dir.Files {
+		asset, err := strconv.ParseUint(file.Hash, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		// Logical assets provided only by variants have no digest of their own.
+		size, sum := int64(-1), make([]byte, 32)
+		if file.SHA256 != "" {
+			size = file.Size
+			if sum, err = hex.DecodeString(file.SHA256); err != nil || len(sum) != 32 {
+				return nil, fmt.Errorf("invalid digest for %s", file.Path)
+			}
+		}
+
+		if len(file.Path) > 0xffff {
+			return nil, fmt.Errorf("path %s is too long", file.Path)
+		}
+
+		binary.Write(buf, binary.LittleEndian, asset)
+		binary.Write(buf, binary.LittleEndian, size)
+		buf.Write(sum)
+		binary.Write(buf, binary.LittleEndian, uint16(len(file.Path)))
+		buf.WriteString(file.Path)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package manifest
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adm87/flinch/engine/resources"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"assets/data/level.json":       `{"level": 1}`,
+		"assets/images/a.png":          "a",
+		"assets/images/splash.png":     "splash",
+		"assets/images/splash.fr.png":  "splash fr",
+		"assets/images/logo@2x.png":    "logo 2x",
+		"assets/images/logo@2x.fr.png": "logo 2x fr",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	model := &Model{Package: "data", Locales: []string{"fr"}}
+	if err := Scan(model, root, "data.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	if err := Serialize(model, out, []string{FormatJSON, FormatBinary}); err != nil {
+		t.Fatal(err)
+	}
+
+	asset := func(name string) resources.Asset {
+		return resources.Asset(HashFNV(name))
+	}
+	digest := func(name string) resources.AssetDigest {
+		return resources.NewAssetDigest([]byte(files[name]))
+	}
+
+	// Variants are grouped under their logical asset, which has the digest of the unsuffixed file if there is
+	// one, and no digest otherwise.
+	wantAssets := resources.AssetManifest{
+		asset("level.json"): "assets/data/level.json",
+		asset("a.png"):      "assets/images/a.png",
+		asset("splash.png"): "assets/images/splash.png",
+		asset("logo.png"):   "assets/images/logo.png",
+	}
+	wantDigests := resources.AssetDigests{
+		asset("level.json"): digest("assets/data/level.json"),
+		asset("a.png"):      digest("assets/images/a.png"),
+		asset("splash.png"): digest("assets/images/splash.png"),
+	}
+
+	for _, name := range []string{"assets.manifest.json", "assets.manifest.bin"} {
+		manifest, err := resources.LoadManifest(os.DirFS(out), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(manifest.Assets, wantAssets) {
+			t.Errorf("%s: got assets %v, want %v", name, manifest.Assets, wantAssets)
+		}
+		if !maps.Equal(manifest.Digests, wantDigests) {
+			t.Errorf("%s: got digests %v, want %v", name, manifest.Digests, wantDigests)
+		}
+	}
+}
@@ -15,13 +15,17 @@ package {{ .Package }}
 
 import (
 	"embed"
+	{{- if .Encrypted }}
+	"io/fs"
+	{{- end }}
+
 	"github.com/adm87/flinch/engine/resources"
 )
 
 // =============== Embedded Directories ===============
 {{ range .Directories }}
 {{ if .IsEmbedded }}
-//go:embed {{ .Path }}
+//go:embed {{ if $.Encrypted }}_encrypted/{{ end }}{{ .Path }}
 var {{ toIdentifier .Name | camel }}EmbeddedFS embed.FS
 {{ end }}
 {{ end }}
@@ -48,6 +52,44 @@ var (
 {{- end }}
 )
 
+// =============== Digests ===============
+
+var (
+{{- range .Directories }}
+	{{ toIdentifier .Name }}Digests = resources.AssetDigests{
+	{{- range .Files }}
+		{{- $file := . }}
+		{{- if .SHA256 }}
+		{{ .Hash }}: {Size: {{ .Size }}, SHA256: "{{ .SHA256 }}"},
+		{{- end }}
+		{{- range .Variants }}
+		{{- if ne .Hash $file.Hash }}
+		{{ .Hash }}: {Size: {{ .Size }}, SHA256: "{{ .SHA256 }}"}, // {{ .Path }}
+		{{- end }}
+		{{- end }}
+	{{- end }}
+	}
+{{- end }}
+)
+
+// =============== Variants ===============
+
+var (
+{{- range .Directories }}
+	{{ toIdentifier .Name }}Variants = resources.AssetVariants{
+	{{- range .Files }}
+		{{- if .Variants }}
+		{{ .Hash }}: {
+		{{- range .Variants }}
+			{ID: {{ .Hash }}, Path: "{{ .Path }}", Locale: "{{ .Locale }}", Scale: {{ .Scale }}},
+		{{- end }}
+		},
+		{{- end }}
+	{{- end }}
+	}
+{{- end }}
+)
+
 // =============== Resource Systems ===============
 
 var (
@@ -55,6 +97,8 @@ var (
 	{{ toIdentifier .Name }} = resources.NewResourceSystem("{{ .Name }}", {{ toIdentifier .Name }}Manifest, 
 		resources.ResourceSystemOptions{
 			TrimRoot: {{ not .IsEmbedded }},
+			Digests:  {{ toIdentifier .Name }}Digests,
+			Variants: {{ toIdentifier .Name }}Variants,
 		},
 	)
 {{- end }}
@@ -65,10 +109,25 @@ var (
 func init() {
 {{- range .Directories }}
 	{{- if .IsEmbedded }}
+	{{- if $.Encrypted }}
+	{{ toIdentifier .Name }}.SetFileSystem(encryptedFS({{ toIdentifier .Name | camel }}EmbeddedFS))
+	{{- else }}
 	{{ toIdentifier .Name }}.SetFileSystem({{ toIdentifier .Name | camel }}EmbeddedFS)
 	{{- end }}
+	{{- end }}
 {{- end }}
 }
+{{ if .Encrypted }}
+// encryptedFS returns the encrypted copy of the embedded directories, rooted so that its paths match the manifests.
+// Its files must be decrypted with crypt.NewFS before they are loaded.
+func encryptedFS(fsys embed.FS) fs.FS {
+	sub, err := fs.Sub(fsys, "_encrypted")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+{{ end }}
 `
 
 func GenerateFromTemplate(model *Model) (string, error) {
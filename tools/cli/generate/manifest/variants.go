@@ -0,0 +1,109 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// variantName matches file names following the variant convention name[@<scale>x][.<locale>].ext,
+// e.g. splash@2x.png, splash.fr.png or splash@2x.pt-BR.png.
+//
+// Many file names have a two-letter suffix that is not a locale, such as tiles.bg.png or font.ui.ttf, so
+// the locale suffix is only recognised for the locales listed in Model.Locales.
+var variantName = regexp.MustCompile(`^(.+?)(?:@(\d+(?:\.\d+)?)x)?(?:\.([a-z]{2}(?:[-_][A-Z]{2})?))?(\.[^.]+)$`)
+
+// parseVariant splits a file name into the name of the logical asset it provides, its scale and its locale.
+//
+// ok is false if the file name does not denote a variant, including when its locale suffix is not one of
+// the given locales.
+func parseVariant(fileName string, locales []string) (name, scale, locale string, ok bool) {
+	match := variantName.FindStringSubmatch(fileName)
+	if match == nil || (match[2] == "" && match[3] == "") {
+		return fileName, "1", "", false
+	}
+	if match[3] != "" && !slices.Contains(locales, match[3]) {
+		return fileName, "1", "", false
+	}
+	return match[1] + match[4], match[2], match[3], true
+}
+
+// groupVariants replaces the files that are variants of the same logical asset with a single file for
+// that asset, listing every file that provides it as a variant, including the unsuffixed file if it exists.
+func groupVariants(files []File, locales []string) []File {
+	groups := make(map[string][]File)
+	for _, file := range files {
+		logical, _, _, _ := parseVariant(path.Base(file.Path), locales)
+		key := path.Join(path.Dir(file.Path), logical)
+		groups[key] = append(groups[key], file)
+	}
+
+	grouped := make([]File, 0, len(groups))
+	for logicalPath, members := range groups {
+		isGrouped := slices.ContainsFunc(members, func(f File) bool {
+			_, _, _, ok := parseVariant(path.Base(f.Path), locales)
+			return ok
+		})
+		if !isGrouped {
+			grouped = append(grouped, members...)
+			continue
+		}
+
+		logicalName := path.Base(logicalPath)
+		logical := File{
+			Path: logicalPath,
+			Name: strings.TrimSuffix(logicalName, path.Ext(logicalName)),
+			Hash: fmt.Sprintf("0x%x", HashFNV(logicalName)),
+		}
+
+		for _, member := range members {
+			_, scale, locale, _ := parseVariant(path.Base(member.Path), locales)
+			if scale == "" {
+				scale = "1"
+			}
+
+			if member.Path == logicalPath {
+				logical.Size = member.Size
+				logical.SHA256 = member.SHA256
+			}
+
+			logical.Variants = append(logical.Variants, Variant{
+				Path:   member.Path,
+				Hash:   member.Hash,
+				Locale: locale,
+				Scale:  scale,
+				Size:   member.Size,
+				SHA256: member.SHA256,
+			})
+		}
+
+		slices.SortFunc(logical.Variants, func(v1, v2 Variant) int {
+			return strings.Compare(v1.Path, v2.Path)
+		})
+
+		grouped = append(grouped, logical)
+	}
+
+	return grouped
+}
+
+// ReportVariants writes the files grouped under each logical asset of the model to w, so that unintended
+// groupings can be spotted in the generator output.
+func ReportVariants(w io.Writer, model *Model) {
+	for _, dir := range model.Directories {
+		for _, file := range dir.Files {
+			if len(file.Variants) == 0 {
+				continue
+			}
+
+			paths := make([]string, len(file.Variants))
+			for i, variant := range file.Variants {
+				paths[i] = variant.Path
+			}
+			fmt.Fprintf(w, "%s: %d variants (%s)\n", file.Path, len(file.Variants), strings.Join(paths, ", "))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseVariant(t *testing.T) {
+	locales := []string{"fr", "pt-BR"}
+
+	tests := []struct {
+		fileName            string
+		name, scale, locale string
+		ok                  bool
+	}{
+		{"splash.png", "splash.png", "1", "", false},
+		{"splash@2x.png", "splash.png", "2", "", true},
+		{"splash.fr.png", "splash.png", "", "fr", true},
+		{"splash@1.5x.pt-BR.png", "splash.png", "1.5", "pt-BR", true},
+		{"tiles.bg.png", "tiles.bg.png", "1", "", false},
+		{"font.ui.ttf", "font.ui.ttf", "1", "", false},
+		{"tiles.bg@2x.png", "tiles.bg.png", "2", "", true},
+	}
+
+	for _, test := range tests {
+		name, scale, locale, ok := parseVariant(test.fileName, locales)
+		if name != test.name || scale != test.scale || locale != test.locale || ok != test.ok {
+			t.Errorf("parseVariant(%q) = (%q, %q, %q, %v), want (%q, %q, %q, %v)",
+				test.fileName, name, scale, locale, ok, test.name, test.scale, test.locale, test.ok)
+		}
+	}
+}
+
+func TestGroupVariants(t *testing.T) {
+	files := []File{
+		{Path: "images/splash.png"},
+		{Path: "images/splash.fr.png"},
+		{Path: "images/tiles.png"},
+		{Path: "images/tiles.bg.png"},
+	}
+
+	model := &Model{
+		Directories: []Directory{{Name: "images", Files: groupVariants(files, []string{"fr"})}},
+	}
+
+	paths := make([]string, 0)
+	for _, file := range model.Directories[0].Files {
+		paths = append(paths, file.Path)
+	}
+	slices.Sort(paths)
+	if want := []string{"images/splash.png", "images/tiles.bg.png", "images/tiles.png"}; !slices.Equal(paths, want) {
+		t.Fatalf("got files %v, want %v", paths, want)
+	}
+
+	out := &bytes.Buffer{}
+	ReportVariants(out, model)
+	if got := strings.TrimSpace(out.String()); got != "images/splash.png: 2 variants (images/splash.fr.png, images/splash.png)" {
+		t.Fatalf("got report %q", got)
+	}
+}
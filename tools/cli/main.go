@@ -4,6 +4,9 @@ import (
 	"os"
 
 	"github.com/adm87/flinch/tools/cli/generate"
+	"github.com/adm87/flinch/tools/cli/pack"
+	"github.com/adm87/flinch/tools/cli/serve"
+	"github.com/adm87/flinch/tools/cli/verify"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +28,9 @@ func main() {
 
 	command.AddCommand(
 		generate.Command(),
+		pack.Command(),
+		serve.Command(),
+		verify.Command(),
 	)
 
 	if err := command.Execute(); err != nil {
@@ -0,0 +1,64 @@
+package pack
+
+import (
+	"path/filepath"
+
+	"github.com/adm87/flinch/tools/cli/crypt"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	var (
+		directory string
+		output    string
+		key       string
+	)
+
+	options := Options{
+		Alignment: DefaultAlignment,
+	}
+
+	command := &cobra.Command{
+		Use:   "pack",
+		Short: "Pack a directory of assets into a single archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workingDir, err := cmd.Flags().GetString("working-dir")
+			if err != nil {
+				return err
+			}
+
+			absPath, err := filepath.Abs(workingDir)
+			if err != nil {
+				return err
+			}
+
+			if key != "" {
+				if options.Key, err = crypt.ParseKey(key); err != nil {
+					return err
+				}
+			}
+
+			return Pack(resolve(absPath, directory), resolve(absPath, output), options)
+		},
+	}
+
+	command.Flags().StringVarP(&directory, "dir", "d", directory, "Directory to pack, relative to the working directory")
+	command.Flags().StringVarP(&output, "output", "o", output, "Output path for the pack file")
+	command.Flags().BoolVarP(&options.Compress, "compress", "z", options.Compress, "Compress entries with DEFLATE when it reduces their size")
+	command.Flags().Uint32Var(&options.Alignment, "align", options.Alignment, "Alignment of entry data in bytes")
+	command.Flags().StringVar(&key, "key", key, "Hex-encoded AES key to encrypt entries with")
+	command.Flags().BoolVar(&options.KeepRoot, "keep-root", options.KeepRoot, "Keep the packed directory name as the root of entry paths")
+
+	command.MarkFlagRequired("dir")
+	command.MarkFlagRequired("output")
+
+	return command
+}
+
+// resolve returns path relative to the working directory, unless it is already absolute.
+func resolve(workingDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir, path)
+}
@@ -0,0 +1,237 @@
+package pack
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adm87/flinch/tools/cli/crypt"
+	"github.com/adm87/flinch/tools/cli/generate/manifest"
+)
+
+// The pack format is documented in the engine pack package. Entry identifiers are computed
+// with the same hash as the generated manifests so that packed assets match their Asset values.
+const (
+	magic   = "FLPK"
+	version = 1
+
+	headerSize = 32
+
+	compressionNone    = 0
+	compressionDeflate = 1
+
+	DefaultAlignment = 16
+)
+
+type Options struct {
+	Compress  bool   // Compress entries when it reduces their size
+	Alignment uint32 // Alignment of entry data in bytes
+	KeepRoot  bool   // Keep the packed directory name as the root of entry paths
+	Key       []byte // AES key to encrypt entries with, or nil to store them in the clear
+}
+
+type entry struct {
+	asset       uint64
+	path        string
+	offset      uint64
+	size        uint64
+	rawSize     uint64
+	compression uint8
+}
+
+// Pack writes every file under directory into a pack file at output.
+func Pack(directory string, output string, options Options) error {
+	if options.Alignment == 0 {
+		options.Alignment = 1
+	}
+
+	files, err := collect(directory, options.KeepRoot)
+	if err != nil {
+		return err
+	}
+
+	var sealer *crypt.Sealer
+	if options.Key != nil {
+		if sealer, err = crypt.NewSealer(options.Key); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Reserve space for the header, which is written once the index location is known.
+	offset := uint64(headerSize)
+	if _, err := out.Write(make([]byte, headerSize)); err != nil {
+		return err
+	}
+
+	entries := make([]entry, 0, len(files))
+	assets := make(map[uint64]string, len(files))
+
+	for _, f := range files {
+		e := entry{
+			asset: manifest.HashFNV(filepath.Base(f.path)),
+			path:  f.path,
+		}
+
+		// Entry paths are stored with a 16-bit length.
+		if len(e.path) > math.MaxUint16 {
+			return fmt.Errorf("path of %s is longer than %d bytes", e.path, math.MaxUint16)
+		}
+
+		if other, exists := assets[e.asset]; exists {
+			return fmt.Errorf("assets %s and %s share the identifier 0x%x", other, e.path, e.asset)
+		}
+		assets[e.asset] = e.path
+
+		data, err := os.ReadFile(f.source)
+		if err != nil {
+			return err
+		}
+
+		// Entries are encrypted before compression, so that the pack reader serves the encrypted files.
+		// Encrypted entries rarely compress, and are then stored as-is.
+		if sealer != nil {
+			if data, err = sealer.Seal(e.path, data); err != nil {
+				return err
+			}
+		}
+		e.rawSize = uint64(len(data))
+
+		if options.Compress {
+			compressed, err := deflate(data)
+			if err != nil {
+				return err
+			}
+			if len(compressed) < len(data) {
+				data = compressed
+				e.compression = compressionDeflate
+			}
+		}
+
+		padding := align(offset, options.Alignment) - offset
+		if _, err := out.Write(make([]byte, padding)); err != nil {
+			return err
+		}
+		offset += padding
+
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+
+		e.offset = offset
+		e.size = uint64(len(data))
+		offset += e.size
+
+		entries = append(entries, e)
+	}
+
+	index := encodeIndex(entries)
+	if _, err := out.Write(index); err != nil {
+		return err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint16(header[4:], version)
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(entries)))
+	binary.LittleEndian.PutUint32(header[12:], options.Alignment)
+	binary.LittleEndian.PutUint64(header[16:], offset)
+	binary.LittleEndian.PutUint64(header[24:], uint64(len(index)))
+
+	if _, err := out.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+type packedFile struct {
+	source string // Path of the file on disk
+	path   string // Slash-separated path of the entry within the pack
+}
+
+func collect(directory string, keepRoot bool) ([]packedFile, error) {
+	files := make([]packedFile, 0)
+
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		if keepRoot {
+			relPath = filepath.Join(filepath.Base(directory), relPath)
+		}
+
+		files = append(files, packedFile{
+			source: path,
+			path:   filepath.ToSlash(relPath),
+		})
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(files, func(f1, f2 packedFile) int {
+		return strings.Compare(f1.path, f2.path)
+	})
+
+	return files, nil
+}
+
+func encodeIndex(entries []entry) []byte {
+	buf := &bytes.Buffer{}
+	for _, e := range entries {
+		binary.Write(buf, binary.LittleEndian, e.asset)
+		binary.Write(buf, binary.LittleEndian, e.offset)
+		binary.Write(buf, binary.LittleEndian, e.size)
+		binary.Write(buf, binary.LittleEndian, e.rawSize)
+		buf.WriteByte(e.compression)
+		binary.Write(buf, binary.LittleEndian, uint16(len(e.path)))
+		buf.WriteString(e.path)
+	}
+	return buf.Bytes()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	writer, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(writer, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func align(offset uint64, alignment uint32) uint64 {
+	a := uint64(alignment)
+	return (offset + a - 1) / a * a
+}
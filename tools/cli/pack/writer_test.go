@@ -0,0 +1,214 @@
+package pack
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adm87/flinch/tools/cli/generate/manifest"
+)
+
+type decodedEntry struct {
+	entry
+	data []byte
+}
+
+// decodePack decodes a pack file following the format documented in the engine pack package.
+func decodePack(t *testing.T, data []byte) (alignment uint32, entries []decodedEntry) {
+	t.Helper()
+
+	if len(data) < headerSize {
+		t.Fatalf("pack is %d bytes, shorter than its header", len(data))
+	}
+	if got := string(data[:4]); got != magic {
+		t.Fatalf("got magic %q, want %q", got, magic)
+	}
+	if got := binary.LittleEndian.Uint16(data[4:]); got != version {
+		t.Fatalf("got version %d, want %d", got, version)
+	}
+	if got := binary.LittleEndian.Uint16(data[6:]); got != 0 {
+		t.Fatalf("got flags %d, want 0", got)
+	}
+	count := binary.LittleEndian.Uint32(data[8:])
+	alignment = binary.LittleEndian.Uint32(data[12:])
+	indexOffset := binary.LittleEndian.Uint64(data[16:])
+	indexSize := binary.LittleEndian.Uint64(data[24:])
+
+	if indexOffset+indexSize != uint64(len(data)) {
+		t.Fatalf("index spans [%d, %d), want it to end the %d-byte pack", indexOffset, indexOffset+indexSize, len(data))
+	}
+
+	index := bytes.NewReader(data[indexOffset:])
+	end := uint64(headerSize)
+	for i := range count {
+		var e decodedEntry
+		var pathLen uint16
+		for _, field := range []any{&e.asset, &e.offset, &e.size, &e.rawSize, &e.compression, &pathLen} {
+			if err := binary.Read(index, binary.LittleEndian, field); err != nil {
+				t.Fatalf("entry %d: %v", i, err)
+			}
+		}
+		path := make([]byte, pathLen)
+		if _, err := io.ReadFull(index, path); err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		e.path = string(path)
+
+		if e.offset%uint64(alignment) != 0 {
+			t.Errorf("%s: offset %d is not aligned to %d", e.path, e.offset, alignment)
+		}
+		if e.offset < end {
+			t.Errorf("%s: offset %d overlaps the previous entry ending at %d", e.path, e.offset, end)
+		}
+		end = e.offset + e.size
+		if end > indexOffset {
+			t.Fatalf("%s: data ends at %d, past the index at %d", e.path, end, indexOffset)
+		}
+		e.data = data[e.offset:end]
+
+		entries = append(entries, e)
+	}
+	if index.Len() != 0 {
+		t.Errorf("got %d bytes after the last index entry, want 0", index.Len())
+	}
+
+	return alignment, entries
+}
+
+func TestPackFormat(t *testing.T) {
+	noise := make([]byte, 300)
+	rand.Read(noise)
+
+	files := map[string][]byte{
+		"images/noise.bin":    noise,
+		"maps/level.tmx":      []byte(strings.Repeat("<tile gid=\"1\"/>", 64)),
+		"readme.txt":          []byte("r"),
+		"sounds/empty.ogg":    nil,
+		"sounds/music/a.ogg":  []byte(strings.Repeat("a", 100)),
+		"sounds/music/b.ogg":  []byte("b"),
+		"sounds/music/bb.ogg": []byte("bb"),
+	}
+	order := []string{
+		"images/noise.bin",
+		"maps/level.tmx",
+		"readme.txt",
+		"sounds/empty.ogg",
+		"sounds/music/a.ogg",
+		"sounds/music/b.ogg",
+		"sounds/music/bb.ogg",
+	}
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		options Options
+		prefix  string
+	}{
+		{"stored", Options{}, ""},
+		{"aligned", Options{Alignment: DefaultAlignment}, ""},
+		{"compressed", Options{Compress: true, Alignment: 64}, ""},
+		{"keep root", Options{KeepRoot: true, Alignment: 4}, "assets/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "assets.pak")
+			if err := Pack(dir, output, tc.options); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alignment, entries := decodePack(t, data)
+
+			want := max(tc.options.Alignment, 1)
+			if alignment != want {
+				t.Errorf("got alignment %d, want %d", alignment, want)
+			}
+			if len(entries) != len(order) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(order))
+			}
+
+			for i, e := range entries {
+				name := order[i]
+				content := files[name]
+
+				if want := tc.prefix + name; e.path != want {
+					t.Errorf("entry %d: got path %s, want %s", i, e.path, want)
+				}
+				if want := manifest.HashFNV(filepath.Base(name)); e.asset != want {
+					t.Errorf("%s: got asset 0x%x, want 0x%x", name, e.asset, want)
+				}
+				if e.rawSize != uint64(len(content)) {
+					t.Errorf("%s: got raw size %d, want %d", name, e.rawSize, len(content))
+				}
+
+				got := e.data
+				switch e.compression {
+				case compressionNone:
+					if e.size != e.rawSize {
+						t.Errorf("%s: got stored size %d, want %d", name, e.size, e.rawSize)
+					}
+				case compressionDeflate:
+					if !tc.options.Compress {
+						t.Errorf("%s: compressed without the option", name)
+					}
+					if e.size >= e.rawSize {
+						t.Errorf("%s: compressed to %d bytes, not smaller than %d", name, e.size, e.rawSize)
+					}
+					if got, err = io.ReadAll(flate.NewReader(bytes.NewReader(e.data))); err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+				default:
+					t.Fatalf("%s: got compression %d", name, e.compression)
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("%s: got data %q, want %q", name, got, content)
+				}
+			}
+
+			if tc.options.Compress {
+				for _, e := range entries {
+					compressible := e.path == tc.prefix+"maps/level.tmx" || e.path == tc.prefix+"sounds/music/a.ogg"
+					if compressible != (e.compression == compressionDeflate) {
+						t.Errorf("%s: got compression %d", e.path, e.compression)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPackRejectsDuplicateAssets(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a/logo.png", "b/logo.png"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := Pack(dir, filepath.Join(t.TempDir(), "assets.pak"), Options{})
+	if err == nil || !strings.Contains(err.Error(), "share the identifier") {
+		t.Fatalf("got error %v, want a shared identifier error", err)
+	}
+}
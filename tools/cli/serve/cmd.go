@@ -0,0 +1,78 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	var (
+		directory string
+		address   string
+		verbose   bool
+	)
+
+	command := &cobra.Command{
+		Use:   "serve-assets",
+		Short: "Serve a directory of assets over HTTP for games running on another machine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workingDir, err := cmd.Flags().GetString("working-dir")
+			if err != nil {
+				return err
+			}
+
+			absPath, err := filepath.Abs(workingDir)
+			if err != nil {
+				return err
+			}
+
+			dir := directory
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(absPath, dir)
+			}
+
+			var log func(format string, args ...any)
+			if verbose {
+				log = func(format string, args ...any) {
+					fmt.Fprintf(cmd.OutOrStdout(), format+"\n", args...)
+				}
+			}
+
+			handler, err := NewHandler(dir, log)
+			if err != nil {
+				return err
+			}
+			defer handler.Close()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			server := &http.Server{Addr: address, Handler: handler}
+			go func() {
+				<-ctx.Done()
+				server.Shutdown(context.Background())
+			}()
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving %s on %s\n", dir, address)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&directory, "dir", "d", directory, "Directory to serve, relative to the working directory")
+	command.Flags().StringVarP(&address, "addr", "a", ":8080", "Address to listen on")
+	command.Flags().BoolVarP(&verbose, "verbose", "v", verbose, "Log every request")
+
+	command.MarkFlagRequired("dir")
+
+	return command
+}
@@ -0,0 +1,163 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// The protocol is documented in the engine httpfs package. Files are served with http.ServeContent, which
+// handles conditional and HEAD requests, and directories are listed as JSON documents.
+const dirContentType = "application/vnd.flinch.directory+json"
+
+type dirDocument struct {
+	Entries []dirEntry `json:"entries"`
+}
+
+type dirEntry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Handler serves the files of a directory. Requests cannot escape the directory, including through
+// symbolic links.
+type Handler struct {
+	root *os.Root
+	log  func(format string, args ...any)
+}
+
+// NewHandler creates a Handler serving the files of directory. If log is not nil, it is called for each
+// request served.
+func NewHandler(directory string, log func(format string, args ...any)) (*Handler, error) {
+	root, err := os.OpenRoot(directory)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{root: root, log: log}, nil
+}
+
+// Close closes the directory served by the handler.
+func (h *Handler) Close() error {
+	return h.root.Close()
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.serve(recorder, r)
+
+	if h.log != nil {
+		h.log("%s %s %d", r.Method, r.URL.Path, recorder.status)
+	}
+}
+
+// serve responds to a request.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	file, err := h.root.Open(name)
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+
+	if info.IsDir() {
+		h.serveDir(w, r, file, info)
+		return
+	}
+
+	// The ETag changes whenever the file is written, so that clients can revalidate their cached copies.
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// serveDir lists the entries of a directory.
+func (h *Handler) serveDir(w http.ResponseWriter, r *http.Request, dir *os.File, info fs.FileInfo) {
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+
+	document := dirDocument{Entries: make([]dirEntry, 0, len(entries))}
+	for _, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		size := entryInfo.Size()
+		if entry.IsDir() {
+			size = 0
+		}
+
+		// Modification times are truncated to the resolution of the Last-Modified header, so that listings
+		// agree with the information returned for each file.
+		document.Entries = append(document.Entries, dirEntry{
+			Name:    entry.Name(),
+			Dir:     entry.IsDir(),
+			Size:    size,
+			ModTime: entryInfo.ModTime().UTC().Truncate(time.Second),
+		})
+	}
+
+	w.Header().Set("Content-Type", dirContentType)
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	json.NewEncoder(w).Encode(document)
+}
+
+func (h *Handler) error(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		status = http.StatusForbidden
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// statusRecorder records the status of a response for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,51 @@
+package verify
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	var manifestPath string
+
+	command := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify asset files against the digests of a generated manifest",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workingDir, err := cmd.Flags().GetString("working-dir")
+			if err != nil {
+				return err
+			}
+
+			absPath, err := filepath.Abs(workingDir)
+			if err != nil {
+				return err
+			}
+
+			entries, err := ParseManifest(filepath.Join(absPath, manifestPath))
+			if err != nil {
+				return err
+			}
+
+			failures := Verify(absPath, entries)
+			for _, failure := range failures {
+				fmt.Fprintln(cmd.OutOrStdout(), failure)
+			}
+
+			if len(failures) > 0 {
+				return fmt.Errorf("%d of %d assets failed verification", len(failures), len(entries))
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%d assets verified\n", len(entries))
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&manifestPath, "manifest", "m", manifestPath, "Path to the generated manifest.go file")
+
+	command.MarkFlagRequired("manifest")
+
+	return command
+}
@@ -0,0 +1,239 @@
+package verify
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/adm87/flinch/tools/cli/generate/manifest"
+)
+
+// Entry is an asset recorded in a generated manifest along with its expected digest.
+type Entry struct {
+	Hash   string
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+// Failure describes an asset whose file does not match its recorded digest.
+type Failure struct {
+	Entry
+	Reason string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s (%s): %s", f.Path, f.Hash, f.Reason)
+}
+
+// ParseManifest extracts the assets and digests declared in a manifest file generated by flinch-cli.
+//
+// Manifests, digests and variants are paired by their variable name prefix, e.g. AssetsManifest and
+// AssetsDigests. Assets with variants are checked through the files of their variants.
+func ParseManifest(path string) ([]Entry, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]map[string]string)
+	digests := make(map[string]map[string]Entry)
+	variants := make(map[string]map[string][]Entry)
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+
+		for i, name := range spec.Names {
+			if i >= len(spec.Values) {
+				break
+			}
+
+			literal, ok := spec.Values[i].(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+
+			switch typeName(literal.Type) {
+			case "AssetManifest":
+				paths[strings.TrimSuffix(name.Name, "Manifest")] = parsePaths(literal)
+			case "AssetDigests":
+				digests[strings.TrimSuffix(name.Name, "Digests")] = parseDigests(literal)
+			case "AssetVariants":
+				variants[strings.TrimSuffix(name.Name, "Variants")] = parseVariants(literal)
+			}
+		}
+		return false
+	})
+
+	entries := make([]Entry, 0)
+	for prefix, manifestPaths := range paths {
+		for hash, assetPath := range manifestPaths {
+			if files, exists := variants[prefix][hash]; exists {
+				for _, file := range files {
+					entry := digests[prefix][file.Hash]
+					entry.Hash = file.Hash
+					entry.Path = file.Path
+					entries = append(entries, entry)
+				}
+				continue
+			}
+
+			entry := digests[prefix][hash]
+			entry.Hash = hash
+			entry.Path = assetPath
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortFunc(entries, func(e1, e2 Entry) int {
+		return strings.Compare(e1.Path, e2.Path)
+	})
+
+	return entries, nil
+}
+
+// Verify checks every entry against the files under root, returning the entries that do not match.
+//
+// Entries without a recorded digest are only checked for existence.
+func Verify(root string, entries []Entry) []Failure {
+	failures := make([]Failure, 0)
+
+	for _, entry := range entries {
+		size, digest, err := manifest.HashFile(filepath.Join(root, filepath.FromSlash(entry.Path)))
+
+		switch {
+		case err != nil:
+			failures = append(failures, Failure{Entry: entry, Reason: err.Error()})
+		case entry.SHA256 == "":
+			continue
+		case size != entry.Size:
+			failures = append(failures, Failure{Entry: entry, Reason: fmt.Sprintf("expected %d bytes, got %d", entry.Size, size)})
+		case digest != entry.SHA256:
+			failures = append(failures, Failure{Entry: entry, Reason: fmt.Sprintf("expected sha256 %s, got %s", entry.SHA256, digest)})
+		}
+	}
+
+	return failures
+}
+
+func typeName(expr ast.Expr) string {
+	if selector, ok := expr.(*ast.SelectorExpr); ok {
+		return selector.Sel.Name
+	}
+	return ""
+}
+
+func parsePaths(literal *ast.CompositeLit) map[string]string {
+	paths := make(map[string]string)
+	for _, element := range literal.Elts {
+		kv, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if path, ok := stringValue(kv.Value); ok {
+			paths[literalValue(kv.Key)] = path
+		}
+	}
+	return paths
+}
+
+func parseDigests(literal *ast.CompositeLit) map[string]Entry {
+	digests := make(map[string]Entry)
+	for _, element := range literal.Elts {
+		kv, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+
+		value, ok := kv.Value.(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+
+		entry := Entry{}
+		for _, field := range value.Elts {
+			fieldKV, ok := field.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+
+			switch literalValue(fieldKV.Key) {
+			case "Size":
+				entry.Size, _ = strconv.ParseInt(literalValue(fieldKV.Value), 0, 64)
+			case "SHA256":
+				entry.SHA256, _ = stringValue(fieldKV.Value)
+			}
+		}
+
+		digests[literalValue(kv.Key)] = entry
+	}
+	return digests
+}
+
+func parseVariants(literal *ast.CompositeLit) map[string][]Entry {
+	variants := make(map[string][]Entry)
+	for _, element := range literal.Elts {
+		kv, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+
+		list, ok := kv.Value.(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+
+		for _, item := range list.Elts {
+			value, ok := item.(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+
+			entry := Entry{}
+			for _, field := range value.Elts {
+				fieldKV, ok := field.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+
+				switch literalValue(fieldKV.Key) {
+				case "ID":
+					entry.Hash = literalValue(fieldKV.Value)
+				case "Path":
+					entry.Path, _ = stringValue(fieldKV.Value)
+				}
+			}
+
+			key := literalValue(kv.Key)
+			variants[key] = append(variants[key], entry)
+		}
+	}
+	return variants
+}
+
+func literalValue(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Value
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+func stringValue(expr ast.Expr) (string, bool) {
+	literal, ok := expr.(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(literal.Value)
+	return value, err == nil
+}
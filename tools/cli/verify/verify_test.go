@@ -0,0 +1,87 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/adm87/flinch/tools/cli/generate/manifest"
+)
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyGeneratedManifest(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"assets/images/match.png",
+		"assets/images/corrupt.png",
+		"assets/images/missing.png",
+		"assets/images/splash.png",
+		"assets/images/splash.fr.png",
+		"assets/images/logo@2x.png",
+	} {
+		writeFile(t, root, name, name)
+	}
+
+	model := &manifest.Model{Package: "data", Locales: []string{"fr"}}
+	if err := manifest.Scan(model, root, "data.go"); err != nil {
+		t.Fatal(err)
+	}
+	source, err := manifest.GenerateFromTemplate(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "data.go", source)
+
+	// Change the files after the manifest was generated.
+	writeFile(t, root, "assets/images/corrupt.png", "assets/images/corrupt.pnh")
+	writeFile(t, root, "assets/images/splash.fr.png", "assets/images/splash.fr")
+	if err := os.Remove(filepath.Join(root, "assets", "images", "missing.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ParseManifest(filepath.Join(root, "data.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assets with variants are checked through the files of their variants.
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		paths[i] = entry.Path
+		if entry.SHA256 == "" || entry.Size == 0 {
+			t.Errorf("entry %s has no digest", entry.Path)
+		}
+	}
+	want := []string{
+		"assets/images/corrupt.png",
+		"assets/images/logo@2x.png",
+		"assets/images/match.png",
+		"assets/images/missing.png",
+		"assets/images/splash.fr.png",
+		"assets/images/splash.png",
+	}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("got entries %v, want %v", paths, want)
+	}
+
+	failed := make([]string, 0)
+	for _, failure := range Verify(root, entries) {
+		failed = append(failed, failure.Path)
+	}
+	want = []string{"assets/images/corrupt.png", "assets/images/missing.png", "assets/images/splash.fr.png"}
+	if !slices.Equal(failed, want) {
+		t.Fatalf("got failures %v, want %v", failed, want)
+	}
+}